@@ -3,14 +3,23 @@ package main
 import (
 	"agricultural-equipment-store/internal/config"
 	"agricultural-equipment-store/internal/delivery/http"
+	"agricultural-equipment-store/internal/domain"
 	"agricultural-equipment-store/internal/infrastructure/database"
 	"agricultural-equipment-store/internal/infrastructure/logger"
+	"agricultural-equipment-store/internal/infrastructure/mailer"
+	"agricultural-equipment-store/internal/infrastructure/oidc"
+	"agricultural-equipment-store/internal/infrastructure/signing"
 	"agricultural-equipment-store/internal/repository"
 	"agricultural-equipment-store/internal/usecase"
+	"agricultural-equipment-store/internal/utils"
+	"context"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	_ "agricultural-equipment-store/docs" // Import docs for Swagger
 )
@@ -34,6 +43,11 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key created through /api-keys, used by devices and integrations.
+
 func main() {
 	// Load configuration
 	cfg := config.Load()
@@ -41,6 +55,17 @@ func main() {
 	// Initialize logger
 	logger := logger.NewLogger()
 
+	// Initialize token signing keys before anything else, so a misconfigured
+	// deployment fails fast
+	signer, err := newTokenSigner(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize token signing:", err)
+	}
+	mfaSecretKey, err := newMFASecretKey(cfg)
+	if err != nil {
+		log.Fatal("Failed to initialize MFA encryption:", err)
+	}
+
 	// Initialize database
 	db, err := database.NewMongoDB(cfg.Database.URI, cfg.Database.Name)
 	if err != nil {
@@ -48,21 +73,96 @@ func main() {
 	}
 	defer db.Close()
 
+	// Create indexes
+	if err := db.CreateIndexes(); err != nil {
+		logger.Warn("Failed to create indexes: %v", err)
+	}
+
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	failedLoginRepo := repository.NewFailedLoginRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	oidcStateRepo := repository.NewOIDCStateRepository(db)
 	productRepo := repository.NewProductRepository(db)
 	saleRepo := repository.NewSaleRepository(db)
 	categoryRepo := repository.NewCategoryRepository(db)
+	specAttributeRepo := repository.NewSpecAttributeRepository(db)
+	productRevisionRepo := repository.NewProductRevisionRepository(db)
+	compatibilityRepo := repository.NewCompatibilityRepository(db)
+
+	// Initialize mailer
+	mail, err := newMailer(cfg.Mail, logger)
+	if err != nil {
+		log.Fatal("Failed to initialize mailer:", err)
+	}
 
 	// Initialize use cases
-	authUseCase := usecase.NewAuthUseCase(userRepo, cfg.JWT.Secret)
-	productUseCase := usecase.NewProductUseCase(productRepo)
-	inventoryUseCase := usecase.NewInventoryUseCase(productRepo)
-	saleUseCase := usecase.NewSaleUseCase(saleRepo, productRepo)
-	categoryUseCase := usecase.NewCategoryUseCase(categoryRepo)
+	authUseCase := usecase.NewAuthUseCase(userRepo, roleRepo, tokenRepo, failedLoginRepo, apiKeyRepo, mail, logger, usecase.AuthConfig{
+		Signer:           signer,
+		Issuer:           cfg.JWT.Issuer,
+		AccessTokenTTL:   cfg.JWT.AccessTokenTTL,
+		RefreshTokenTTL:  cfg.JWT.RefreshTokenTTL,
+		PasswordResetTTL: cfg.Security.PasswordResetTTL,
+		PasswordResetURL: strings.TrimRight(cfg.Frontend.URL, "/") + "/reset-password",
+
+		EmailVerificationTTL: cfg.Security.EmailVerificationTTL,
+		EmailChangeURL:       strings.TrimRight(cfg.Frontend.URL, "/") + "/confirm-email",
+		EmailVerificationURL: strings.TrimRight(cfg.Frontend.URL, "/") + "/verify-email",
+
+		RequireVerifiedEmailForLogin:    cfg.Security.RequireVerifiedEmailForLogin,
+		RequireVerifiedEmailForPurchase: cfg.Security.RequireVerifiedEmailForPurchase,
+
+		MaxFailedLogins:   cfg.Security.MaxFailedLogins,
+		LoginBackoffBase:  cfg.Security.LoginBackoffBase,
+		LockoutDuration:   cfg.Security.LockoutDuration,
+		IPMaxFailedLogins: cfg.Security.IPMaxFailedLogins,
+		IPLoginWindow:     cfg.Security.IPLoginWindow,
+
+		MFAIssuer:        cfg.Security.MFAIssuer,
+		MFARequiredRoles: cfg.Security.MFARequiredRoles,
+		MFASecretKey:     mfaSecretKey,
+	})
+	userUseCase := usecase.NewUserUseCase(userRepo, roleRepo, tokenRepo, failedLoginRepo, apiKeyRepo)
+	roleUseCase := usecase.NewRoleUseCase(roleRepo, userRepo)
+	apiKeyUseCase := usecase.NewAPIKeyUseCase(apiKeyRepo, userRepo, roleRepo)
+	oidcUseCase, err := newOIDCUseCase(cfg.OIDC, authUseCase, userRepo, roleRepo, oidcStateRepo)
+	if err != nil {
+		log.Fatal("Failed to initialize OIDC login:", err)
+	}
+	productUseCase := usecase.NewProductUseCase(productRepo, specAttributeRepo, productRevisionRepo, compatibilityRepo, logger)
+	inventoryUseCase := usecase.NewInventoryUseCase(productRepo, productRevisionRepo, logger)
+	saleUseCase := usecase.NewSaleUseCase(saleRepo, productRepo, productRevisionRepo, logger)
+	categoryUseCase := usecase.NewCategoryUseCase(categoryRepo, productRepo)
+
+	// Make sure the built-in roles exist
+	if err := roleUseCase.EnsureDefaultRoles(context.Background()); err != nil {
+		log.Fatal("Failed to create default roles:", err)
+	}
+
+	// Accounts from before email verification existed count as verified
+	if _, err := userUseCase.MarkLegacyEmailsVerified(context.Background()); err != nil {
+		log.Fatal("Failed to migrate email verification:", err)
+	}
+
+	// TOTP secrets from before they were encrypted are encrypted in place
+	if _, err := authUseCase.EncryptLegacyMFASecrets(context.Background()); err != nil {
+		log.Fatal("Failed to encrypt MFA secrets:", err)
+	}
+
+	// Products from before popularity sorting existed get their sold count from past sales
+	if _, err := productUseCase.BackfillSoldCounts(context.Background()); err != nil {
+		log.Fatal("Failed to backfill product sold counts:", err)
+	}
+
+	// Empty the product trash in the background
+	if cfg.Products.PurgeInterval > 0 {
+		go purgeDeletedProducts(productUseCase, cfg.Products, logger)
+	}
 
 	// Initialize HTTP server
-	server := http.NewServer(cfg, logger, authUseCase, productUseCase, inventoryUseCase, saleUseCase, categoryUseCase)
+	server := http.NewServer(cfg, logger, authUseCase, userUseCase, roleUseCase, apiKeyUseCase, oidcUseCase, productUseCase, inventoryUseCase, saleUseCase, categoryUseCase)
 
 	// Start server
 	go func() {
@@ -79,3 +179,109 @@ func main() {
 	// Shutdown server
 	server.Shutdown()
 }
+
+// newTokenSigner loads the JWT signing keys. Asymmetric keys are used when
+// JWT_KEYS_DIR is set, otherwise tokens are signed with JWT_SECRET, which must
+// not be the default outside development.
+func newTokenSigner(cfg *config.Config) (*signing.KeySet, error) {
+	if cfg.JWT.KeysDir != "" {
+		return signing.LoadKeySet(cfg.JWT.KeysDir, cfg.JWT.ActiveKeyID)
+	}
+
+	if cfg.JWT.Secret == config.DefaultJWTSecret && !cfg.Server.IsDevelopment() {
+		return nil, fmt.Errorf("JWT_SECRET must be changed from the default or JWT_KEYS_DIR set when APP_ENV is %q", cfg.Server.Env)
+	}
+	return signing.NewHMACKeySet(cfg.JWT.Secret), nil
+}
+
+// developmentMFAEncryptionKey encrypts the MFA secrets in development when
+// MFA_ENCRYPTION_KEY is not set
+const developmentMFAEncryptionKey = "development-mfa-encryption-key"
+
+// newMFASecretKey derives the key the MFA secrets are encrypted with from
+// MFA_ENCRYPTION_KEY. It is kept apart from the JWT keys, so those can be
+// rotated without making the stored secrets unreadable.
+func newMFASecretKey(cfg *config.Config) ([]byte, error) {
+	key := cfg.Security.MFAEncryptionKey
+	if key == "" {
+		if !cfg.Server.IsDevelopment() {
+			return nil, fmt.Errorf("MFA_ENCRYPTION_KEY is required when APP_ENV is %q", cfg.Server.Env)
+		}
+		key = developmentMFAEncryptionKey
+	}
+	return utils.DeriveKey(key, "mfa-secret"), nil
+}
+
+// newOIDCUseCase creates the OpenID Connect login use case, or nil when no
+// identity provider is configured
+func newOIDCUseCase(cfg config.OIDCConfig, authUseCase *usecase.AuthUseCase, userRepo domain.UserRepository, roleRepo domain.RoleRepository, stateRepo domain.OIDCStateRepository) (*usecase.OIDCUseCase, error) {
+	if cfg.IssuerURL == "" {
+		return nil, nil
+	}
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("OIDC_CLIENT_ID is required when OIDC_ISSUER_URL is set")
+	}
+
+	groupRoles := make([]usecase.OIDCGroupRole, 0, len(cfg.GroupRoles))
+	for _, entry := range cfg.GroupRoles {
+		group, role, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(group) == "" || strings.TrimSpace(role) == "" {
+			return nil, fmt.Errorf("invalid OIDC_GROUP_ROLES entry %q, expected group=role", entry)
+		}
+		groupRoles = append(groupRoles, usecase.OIDCGroupRole{Group: strings.TrimSpace(group), Role: strings.TrimSpace(role)})
+	}
+
+	provider := oidc.NewProvider(oidc.Config{
+		IssuerURL:    cfg.IssuerURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectURL,
+		Scopes:       cfg.Scopes,
+		GroupsClaim:  cfg.GroupsClaim,
+	})
+
+	return usecase.NewOIDCUseCase(authUseCase, userRepo, roleRepo, stateRepo, provider, usecase.OIDCConfig{
+		GroupRoles:     groupRoles,
+		DefaultRole:    cfg.DefaultRole,
+		AllowedDomains: cfg.AllowedDomains,
+		AutoProvision:  cfg.AutoProvision,
+	}), nil
+}
+
+// purgeDeletedProducts permanently deletes the products that have been in the
+// trash longer than the retention period, every purge interval
+func purgeDeletedProducts(productUseCase *usecase.ProductUseCase, cfg config.ProductsConfig, logger logger.Logger) {
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := productUseCase.PurgeDeletedProducts(context.Background(), time.Now().Add(-cfg.TrashRetention))
+		if err != nil {
+			logger.Error("Failed to purge deleted products: %v", err)
+		}
+		if purged > 0 {
+			logger.Info("Purged %d deleted products", purged)
+		}
+		<-ticker.C
+	}
+}
+
+// newMailer creates the mailer selected by the MAIL_DRIVER setting
+func newMailer(cfg config.MailConfig, logger logger.Logger) (domain.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.FileDir, cfg.From)
+	case "log":
+		return mailer.NewLogMailer(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
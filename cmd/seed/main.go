@@ -4,13 +4,18 @@ import (
 	"agricultural-equipment-store/internal/config"
 	"agricultural-equipment-store/internal/domain"
 	"agricultural-equipment-store/internal/infrastructure/database"
+	"agricultural-equipment-store/internal/infrastructure/logger"
 	"agricultural-equipment-store/internal/repository"
 	"agricultural-equipment-store/internal/usecase"
 	"context"
+	"flag"
 	"log"
 )
 
 func main() {
+	resetAdminPassword := flag.Bool("reset-admin-password", false, "Reset the password of an existing admin user to ADMIN_PASSWORD")
+	flag.Parse()
+
 	// Load configuration
 	cfg := config.Load()
 
@@ -28,20 +33,33 @@ func main() {
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
+	failedLoginRepo := repository.NewFailedLoginRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
 	productRepo := repository.NewProductRepository(db)
+	specAttributeRepo := repository.NewSpecAttributeRepository(db)
+	productRevisionRepo := repository.NewProductRevisionRepository(db)
+	compatibilityRepo := repository.NewCompatibilityRepository(db)
 
 	// Initialize use cases
-	authUseCase := usecase.NewAuthUseCase(userRepo, cfg.JWT.Secret)
-	productUseCase := usecase.NewProductUseCase(productRepo)
+	roleUseCase := usecase.NewRoleUseCase(roleRepo, userRepo)
+	userUseCase := usecase.NewUserUseCase(userRepo, roleRepo, tokenRepo, failedLoginRepo, apiKeyRepo)
+	productUseCase := usecase.NewProductUseCase(productRepo, specAttributeRepo, productRevisionRepo, compatibilityRepo, logger.NewLogger())
 
 	ctx := context.Background()
 
+	// Create built-in roles
+	if err := roleUseCase.EnsureDefaultRoles(ctx); err != nil {
+		log.Fatal("Failed to create default roles:", err)
+	}
+
 	// Create admin user
-	adminReq := domain.CreateUserRequest{
+	adminReq := domain.AdminCreateUserRequest{
 		Email:    cfg.Admin.Email,
 		Password: cfg.Admin.Password,
 		Name:     "Administrator",
-		Role:     "admin",
+		Role:     domain.RoleAdmin,
 	}
 
 	existingAdmin, err := userRepo.GetByEmail(ctx, adminReq.Email)
@@ -50,15 +68,37 @@ func main() {
 	}
 
 	if existingAdmin == nil {
-		_, err = authUseCase.Register(ctx, adminReq)
+		_, err = userUseCase.CreateUser(ctx, []string{domain.PermissionAll}, adminReq)
 		if err != nil {
 			log.Fatal("Failed to create admin user:", err)
 		}
 		log.Println("Admin user created successfully")
+	} else if *resetAdminPassword {
+		if err := userUseCase.SetPassword(ctx, existingAdmin.ID, adminReq.Password); err != nil {
+			log.Fatal("Failed to reset admin password:", err)
+		}
+		log.Println("Admin password reset successfully")
 	} else {
 		log.Println("Admin user already exists")
 	}
 
+	// Create spec attributes
+	specAttributes := []domain.CreateSpecAttributeRequest{
+		{Key: "hp", Name: "Engine power", Type: domain.SpecTypeNumber, Unit: "hp"},
+		{Key: "fuel", Name: "Fuel type", Type: domain.SpecTypeEnum, Values: []string{"diesel", "petrol", "electric"}},
+		{Key: "four_wheel_drive", Name: "Four-wheel drive", Type: domain.SpecTypeBoolean},
+		{Key: "working_width", Name: "Working width", Type: domain.SpecTypeNumber, Unit: "cm"},
+	}
+
+	for _, attributeReq := range specAttributes {
+		_, err = productUseCase.CreateSpecAttribute(ctx, attributeReq)
+		if err != nil {
+			log.Printf("Failed to create spec attribute %s: %v", attributeReq.Key, err)
+		} else {
+			log.Printf("Spec attribute created: %s", attributeReq.Key)
+		}
+	}
+
 	// Create sample products
 	sampleProducts := []domain.CreateProductRequest{
 		{
@@ -69,6 +109,11 @@ func main() {
 			Brand:       "John Deere",
 			ImageURL:    "https://example.com/images/john-deere-x350.jpg",
 			Stock:       15,
+			Specs: []domain.ProductSpec{
+				{Key: "hp", Value: 17.5},
+				{Key: "fuel", Value: "petrol"},
+				{Key: "working_width", Value: 107.0},
+			},
 		},
 		{
 			Name:        "Husqvarna 450 Chainsaw",
@@ -87,6 +132,11 @@ func main() {
 			Brand:       "Kubota",
 			ImageURL:    "https://example.com/images/kubota-bx23s.jpg",
 			Stock:       8,
+			Specs: []domain.ProductSpec{
+				{Key: "hp", Value: 23.0},
+				{Key: "fuel", Value: "diesel"},
+				{Key: "four_wheel_drive", Value: true},
+			},
 		},
 		{
 			Name:        "STIHL MS 170 Chainsaw",
@@ -105,6 +155,11 @@ func main() {
 			Brand:       "Troy-Bilt",
 			ImageURL:    "https://example.com/images/troy-bilt-pony42.jpg",
 			Stock:       12,
+			Specs: []domain.ProductSpec{
+				{Key: "hp", Value: 17.5},
+				{Key: "fuel", Value: "petrol"},
+				{Key: "working_width", Value: 107.0},
+			},
 		},
 	}
 
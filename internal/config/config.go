@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -15,6 +17,10 @@ type Config struct {
 	Server   ServerConfig
 	Frontend FrontendConfig
 	Admin    AdminConfig
+	Mail     MailConfig
+	Security SecurityConfig
+	OIDC     OIDCConfig
+	Products ProductsConfig
 }
 
 // DatabaseConfig holds database configuration
@@ -23,14 +29,29 @@ type DatabaseConfig struct {
 	Name string
 }
 
+// DefaultJWTSecret is the JWT secret used when JWT_SECRET is not set. It is
+// only accepted in development.
+const DefaultJWTSecret = "your-secret-key"
+
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
-	Secret string
+	Secret          string // HS256 secret, used when KeysDir is empty
+	KeysDir         string // Directory of <kid>.pem RSA or Ed25519 keys
+	ActiveKeyID     string // Key in KeysDir that signs new tokens
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	Port string
+	Env  string // development, staging or production
+}
+
+// IsDevelopment reports whether the server runs in the development environment
+func (c ServerConfig) IsDevelopment() bool {
+	return c.Env == "development"
 }
 
 // FrontendConfig holds frontend configuration
@@ -44,6 +65,57 @@ type AdminConfig struct {
 	Password string
 }
 
+// MailConfig holds email delivery configuration
+type MailConfig struct {
+	Driver       string // smtp, file or log
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	FileDir      string // Directory used by the file driver
+}
+
+// SecurityConfig holds account security configuration
+type SecurityConfig struct {
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+
+	RequireVerifiedEmailForLogin    bool
+	RequireVerifiedEmailForPurchase bool
+
+	MaxFailedLogins   int
+	LoginBackoffBase  time.Duration
+	LockoutDuration   time.Duration
+	IPMaxFailedLogins int
+	IPLoginWindow     time.Duration
+
+	MFAIssuer        string
+	MFARequiredRoles []string
+	MFAEncryptionKey string // Encrypts the TOTP secrets in the database, required outside development
+}
+
+// OIDCConfig holds OpenID Connect identity provider configuration. Login
+// through the provider is disabled when IssuerURL is empty.
+type OIDCConfig struct {
+	IssuerURL      string
+	ClientID       string
+	ClientSecret   string
+	RedirectURL    string
+	Scopes         []string
+	GroupsClaim    string
+	GroupRoles     []string // group=role pairs, the first group the user is in decides the role
+	DefaultRole    string
+	AllowedDomains []string
+	AutoProvision  bool
+}
+
+// ProductsConfig holds product catalog configuration
+type ProductsConfig struct {
+	TrashRetention time.Duration // How long deleted products can be restored
+	PurgeInterval  time.Duration // How often the trash is purged, 0 disables purging
+}
+
 // Load loads configuration from environment variables
 func Load() *Config {
 	// Load .env file
@@ -57,10 +129,16 @@ func Load() *Config {
 			Name: getEnv("MONGODB_DATABASE", "agricultural"),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key"),
+			Secret:          getEnv("JWT_SECRET", DefaultJWTSecret),
+			KeysDir:         getEnv("JWT_KEYS_DIR", ""),
+			ActiveKeyID:     getEnv("JWT_ACTIVE_KEY_ID", ""),
+			Issuer:          getEnv("JWT_ISSUER", "agricultural-equipment-store"),
+			AccessTokenTTL:  getEnvAsDuration("JWT_ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL: getEnvAsDuration("JWT_REFRESH_TOKEN_TTL", 7*24*time.Hour),
 		},
 		Server: ServerConfig{
 			Port: getEnv("PORT", "8082"),
+			Env:  getEnv("APP_ENV", "development"),
 		},
 		Frontend: FrontendConfig{
 			URL: getEnv("FRONTEND_URL", "http://localhost:3000"),
@@ -69,6 +147,48 @@ func Load() *Config {
 			Email:    getEnv("ADMIN_EMAIL", "admin@agricultural.com"),
 			Password: getEnv("ADMIN_PASSWORD", "password123"),
 		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "no-reply@agricultural.com"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnvAsInt("SMTP_PORT", 587),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FileDir:      getEnv("MAIL_FILE_DIR", "./mail"),
+		},
+		Security: SecurityConfig{
+			PasswordResetTTL:     getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour),
+			EmailVerificationTTL: getEnvAsDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+
+			RequireVerifiedEmailForLogin:    getEnvAsBool("REQUIRE_VERIFIED_EMAIL_FOR_LOGIN", false),
+			RequireVerifiedEmailForPurchase: getEnvAsBool("REQUIRE_VERIFIED_EMAIL_FOR_PURCHASE", false),
+
+			MaxFailedLogins:   getEnvAsInt("LOGIN_MAX_FAILED_ATTEMPTS", 5),
+			LoginBackoffBase:  getEnvAsDuration("LOGIN_BACKOFF_BASE", time.Second),
+			LockoutDuration:   getEnvAsDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+			IPMaxFailedLogins: getEnvAsInt("LOGIN_IP_MAX_FAILED_ATTEMPTS", 20),
+			IPLoginWindow:     getEnvAsDuration("LOGIN_IP_WINDOW", 15*time.Minute),
+
+			MFAIssuer:        getEnv("MFA_ISSUER", "Agricultural Equipment Store"),
+			MFARequiredRoles: getEnvAsSlice("MFA_REQUIRED_ROLES", nil),
+			MFAEncryptionKey: getEnv("MFA_ENCRYPTION_KEY", ""),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:      getEnv("OIDC_ISSUER_URL", ""),
+			ClientID:       getEnv("OIDC_CLIENT_ID", ""),
+			ClientSecret:   getEnv("OIDC_CLIENT_SECRET", ""),
+			RedirectURL:    getEnv("OIDC_REDIRECT_URL", strings.TrimRight(getEnv("FRONTEND_URL", "http://localhost:3000"), "/")+"/oidc/callback"),
+			Scopes:         getEnvAsSlice("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			GroupsClaim:    getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			GroupRoles:     getEnvAsSlice("OIDC_GROUP_ROLES", nil),
+			DefaultRole:    getEnv("OIDC_DEFAULT_ROLE", "user"),
+			AllowedDomains: getEnvAsSlice("OIDC_ALLOWED_DOMAINS", nil),
+			AutoProvision:  getEnvAsBool("OIDC_AUTO_PROVISION", true),
+		},
+		Products: ProductsConfig{
+			TrashRetention: getEnvAsDuration("PRODUCT_TRASH_RETENTION", 30*24*time.Hour),
+			PurgeInterval:  getEnvAsDuration("PRODUCT_PURGE_INTERVAL", time.Hour),
+		},
 	}
 }
 
@@ -99,3 +219,29 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvAsDuration gets environment variable as duration with default value
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if durationValue, err := time.ParseDuration(value); err == nil {
+			return durationValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsSlice gets a comma separated environment variable as a slice with default value
+func getEnvAsSlice(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,196 @@
+package http
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/usecase"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyHandler handles API key management endpoints
+type APIKeyHandler struct {
+	apiKeyUseCase *usecase.APIKeyUseCase
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyUseCase *usecase.APIKeyUseCase) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyUseCase: apiKeyUseCase,
+	}
+}
+
+// CreateAPIKey handles creating an API key
+// @Summary Create API key
+// @Description Create an API key acting as a user, limited to the given scopes. The key is only returned once.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.CreateAPIKeyRequest true "API key creation request"
+// @Success 201 {object} domain.CreateAPIKeyResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req domain.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.apiKeyUseCase.CreateAPIKey(c.Request.Context(), actorID, req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetAPIKeys handles listing API keys
+// @Summary List API keys
+// @Description List API keys, without the keys themselves
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query string false "User ID filter"
+// @Param include_revoked query bool false "Include revoked keys"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 10)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /api-keys [get]
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	var filter domain.APIKeyFilter
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := primitive.ObjectIDFromHex(userIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	if includeRevokedStr := c.Query("include_revoked"); includeRevokedStr != "" {
+		if includeRevoked, err := strconv.ParseBool(includeRevokedStr); err == nil {
+			filter.IncludeRevoked = includeRevoked
+		}
+	}
+
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil {
+			filter.Page = page
+		}
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			filter.Limit = limit
+		}
+	}
+
+	keys, count, err := h.apiKeyUseCase.ListAPIKeys(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// ListAPIKeys applies the default pagination, mirror it for the response
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	totalPages := (count + int64(filter.Limit) - 1) / int64(filter.Limit)
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys":    keys,
+		"total":       count,
+		"page":        filter.Page,
+		"limit":       filter.Limit,
+		"total_pages": totalPages,
+	})
+}
+
+// GetAPIKey handles getting an API key by ID
+// @Summary Get API key by ID
+// @Description Get a single API key, without the key itself
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} domain.APIKey
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api-keys/{id} [get]
+func (h *APIKeyHandler) GetAPIKey(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+
+	key, err := h.apiKeyUseCase.GetAPIKey(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// RevokeAPIKey handles revoking an API key
+// @Summary Revoke API key
+// @Description Revoke an API key, it stops working immediately
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid API key ID"})
+		return
+	}
+
+	if err := h.apiKeyUseCase.RevokeAPIKey(c.Request.Context(), id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
+
+// handleError maps API key errors to HTTP responses
+func (h *APIKeyHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "API key not found", err.Error() == "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "invalid user ID",
+		err.Error() == "user account is inactive",
+		err.Error() == "expiry must be in the future",
+		strings.HasPrefix(err.Error(), "unknown permission"),
+		strings.HasPrefix(err.Error(), "scope "):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
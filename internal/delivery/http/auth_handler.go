@@ -3,7 +3,11 @@ package http
 import (
 	"agricultural-equipment-store/internal/domain"
 	"agricultural-equipment-store/internal/usecase"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -54,7 +58,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 // Login handles user login
 // @Summary Login user
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return a short-lived JWT access token and a refresh token. Users with two-factor authentication get an MFA challenge (domain.MFAChallengeResponse) to complete at /auth/mfa/verify instead. Unverified emails are refused with 403 when verification is required for login.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -62,6 +66,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Success 200 {object} domain.LoginResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]string
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req domain.LoginRequest
@@ -69,27 +75,220 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
 
-	response, err := h.authUseCase.Login(c.Request.Context(), req)
+	response, challenge, err := h.authUseCase.Login(c.Request.Context(), req)
 	if err != nil {
+		if writeThrottled(c, err) {
+			return
+		}
+		if err.Error() == "email address is not verified" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
+// GetJWKS handles publishing the token verification keys
+// @Summary Get JSON Web Key Set
+// @Description Public keys that verify access tokens, selected by the kid header of a token. Empty when tokens are signed with a shared secret.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} domain.JSONWebKeySet
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) GetJWKS(c *gin.Context) {
+	// Clients may cache the keys, a rotated key stays in the set until its tokens expire
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authUseCase.GetJWKS())
+}
+
 // GetProfile handles getting user profile
 // @Summary Get user profile
-// @Description Get current user profile
+// @Description Get the current user with the permissions of their role and their active login sessions
 // @Tags auth
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} domain.User
+// @Success 200 {object} domain.ProfileResponse
 // @Failure 401 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /auth/profile [get]
 func (h *AuthHandler) GetProfile(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	profile, err := h.authUseCase.GetProfile(c.Request.Context(), id)
+	if err != nil {
+		h.handleProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateProfile handles updating the current user
+// @Summary Update user profile
+// @Description Update the name and email of the current user. A new email requires the current password and only takes effect once confirmed from a link sent to it; until then it is shown as pending_email.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.UpdateProfileRequest true "Update profile request"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/profile [put]
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req domain.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authUseCase.UpdateProfile(c.Request.Context(), id, req)
+	if err != nil {
+		h.handleProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ConfirmEmailChange handles confirming a new email address
+// @Summary Confirm email change
+// @Description Apply a pending email change using the token from the confirmation email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.ConfirmEmailChangeRequest true "Confirm email change request"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/profile/confirm-email [post]
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	var req domain.ConfirmEmailChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authUseCase.ConfirmEmailChange(c.Request.Context(), req)
+	if err != nil {
+		h.handleProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteProfile handles closing the current user's account
+// @Summary Delete account
+// @Description Permanently delete the current user after checking their password and, when two-factor authentication is enabled, a code. All sessions are ended.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.DeleteAccountRequest true "Delete account request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/profile [delete]
+func (h *AuthHandler) DeleteProfile(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req domain.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	tokenExpiresAt := c.GetTime("token_expires_at")
+	if tokenExpiresAt.IsZero() {
+		tokenExpiresAt = time.Now().Add(24 * time.Hour)
+	}
+
+	if err := h.authUseCase.DeleteAccount(c.Request.Context(), id, c.GetString("token_id"), tokenExpiresAt, req); err != nil {
+		h.handleProfileError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "account deleted successfully"})
+}
+
+// RefreshToken handles exchanging a refresh token for a new token pair
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token. The refresh token is rotated and the old one can no longer be used.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.RefreshTokenRequest true "Refresh token request"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/refresh [post]
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req domain.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	response, err := h.authUseCase.RefreshToken(c.Request.Context(), req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid refresh token", "refresh token expired", "user account is inactive":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Logout handles user logout
+// @Summary Logout user
+// @Description Revoke the current access token and end the session of the given refresh token, or all sessions of the user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.LogoutRequest false "Logout request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
@@ -102,16 +301,395 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	user, err := h.authUseCase.GetUserByID(c.Request.Context(), id)
-	if err != nil {
+	// The body is optional, an empty body only revokes the access token
+	var req domain.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	tokenID := c.GetString("token_id")
+	tokenExpiresAt := c.GetTime("token_expires_at")
+	if tokenExpiresAt.IsZero() {
+		tokenExpiresAt = time.Now().Add(24 * time.Hour)
+	}
+
+	if err := h.authUseCase.Logout(c.Request.Context(), id, tokenID, tokenExpiresAt, req); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if user == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	c.JSON(http.StatusOK, gin.H{"message": "logged out successfully"})
+}
+
+// ForgotPassword handles requesting a password reset
+// @Summary Request password reset
+// @Description Email a one-time password reset link. The response is the same whether or not the email is registered.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.ForgotPasswordRequest true "Forgot password request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req domain.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUseCase.ForgotPassword(c.Request.Context(), req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "if the email is registered, a password reset link has been sent"})
+}
+
+// VerifyEmail handles verifying an email address
+// @Summary Verify email
+// @Description Mark the email of a user as verified using the token from the verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.VerifyEmailRequest true "Verify email request"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req domain.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.authUseCase.VerifyEmail(c.Request.Context(), req)
+	if err != nil {
+		if err.Error() == "invalid or expired verification token" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, user)
 }
+
+// ResendVerification handles requesting a new verification email
+// @Summary Resend verification email
+// @Description Email a new verification link. The response is the same whether or not the email is registered or already verified.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.ResendVerificationRequest true "Resend verification request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/resend-verification [post]
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req domain.ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUseCase.ResendVerificationEmail(c.Request.Context(), req); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "if the email is registered and not yet verified, a verification link has been sent"})
+}
+
+// ResetPassword handles resetting a password with a reset token
+// @Summary Reset password
+// @Description Set a new password using the token from a password reset email. All sessions of the user are ended.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.ResetPasswordRequest true "Reset password request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req domain.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUseCase.ResetPassword(c.Request.Context(), req); err != nil {
+		if err.Error() == "invalid or expired reset token" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
+
+// ChangePassword handles changing the password of the current user
+// @Summary Change password
+// @Description Change the password of the current user. All sessions of the user are ended.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.ChangePasswordRequest true "Change password request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/password [put]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req domain.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authUseCase.ChangePassword(c.Request.Context(), id, req); err != nil {
+		switch err.Error() {
+		case "current password is incorrect", "new password must be different from the current password":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed successfully"})
+}
+
+// VerifyMFA handles completing a login with a second factor
+// @Summary Verify MFA code
+// @Description Exchange the MFA token from login and an authenticator code or a recovery code for an access token and a refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.MFAVerifyRequest true "MFA verification request"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req domain.MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	response, err := h.authUseCase.VerifyMFA(c.Request.Context(), req)
+	if err != nil {
+		if writeThrottled(c, err) {
+			return
+		}
+		switch err.Error() {
+		case "code or recovery code required":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "invalid or expired MFA token", "invalid MFA code":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SetupMFA handles starting two-factor authentication enrollment
+// @Summary Start MFA enrollment
+// @Description Generate a TOTP secret and the otpauth:// provisioning URI to show as a QR code. MFA is enabled once a code is confirmed at /auth/mfa/enable.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} domain.MFASetupResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/mfa/setup [post]
+func (h *AuthHandler) SetupMFA(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	response, err := h.authUseCase.SetupMFA(c.Request.Context(), id)
+	if err != nil {
+		h.handleMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// EnableMFA handles confirming two-factor authentication enrollment
+// @Summary Enable MFA
+// @Description Confirm enrollment with a code from the authenticator app. Returns recovery codes, which are only shown once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.MFACodeRequest true "MFA code"
+// @Success 200 {object} domain.MFARecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /auth/mfa/enable [post]
+func (h *AuthHandler) EnableMFA(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req domain.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.authUseCase.EnableMFA(c.Request.Context(), id, req)
+	if err != nil {
+		h.handleMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DisableMFA handles turning off two-factor authentication
+// @Summary Disable MFA
+// @Description Turn off two-factor authentication. Requires the password and a code, and is refused for roles that require MFA.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.MFADisableRequest true "MFA disable request"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/mfa/disable [post]
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req domain.MFADisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	if err := h.authUseCase.DisableMFA(c.Request.Context(), id, req); err != nil {
+		h.handleMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes handles replacing the MFA recovery codes
+// @Summary Regenerate recovery codes
+// @Description Replace the recovery codes after confirming a code. The old codes stop working.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.MFACodeRequest true "MFA code"
+// @Success 200 {object} domain.MFARecoveryCodesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]string
+// @Router /auth/mfa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	id, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	var req domain.MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	response, err := h.authUseCase.RegenerateRecoveryCodes(c.Request.Context(), id, req)
+	if err != nil {
+		h.handleMFAError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleProfileError maps profile errors to HTTP responses
+func (h *AuthHandler) handleProfileError(c *gin.Context, err error) {
+	if writeThrottled(c, err) {
+		return
+	}
+
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "current password is required to change the email", "current password is incorrect",
+		"password is incorrect", "invalid MFA code", "invalid or expired email confirmation token":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "email is already in use", "cannot delete the last administrator account":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// handleMFAError maps MFA enrollment errors to HTTP responses
+func (h *AuthHandler) handleMFAError(c *gin.Context, err error) {
+	if writeThrottled(c, err) {
+		return
+	}
+
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "MFA is already enabled", "MFA is not enabled", "MFA setup has not been started",
+		"MFA is required for this role", "invalid MFA code", "password is incorrect":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// writeThrottled responds with 429 and a Retry-After header when a login was throttled
+func writeThrottled(c *gin.Context, err error) bool {
+	var throttled *usecase.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	return true
+}
@@ -49,6 +49,7 @@ func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 		return
 	}
 
+	setETag(c, category.Version)
 	c.JSON(http.StatusCreated, category)
 }
 
@@ -79,6 +80,7 @@ func (h *CategoryHandler) GetCategories(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Category ID"
 // @Success 200 {object} domain.Category "Category found"
+// @Header 200 {string} ETag "Category version, sent as If-Match to change the category"
 // @Failure 400 {object} map[string]string "Invalid ID format"
 // @Failure 404 {object} map[string]string "Category not found"
 // @Failure 500 {object} map[string]string "Internal server error"
@@ -96,6 +98,55 @@ func (h *CategoryHandler) GetCategory(c *gin.Context) {
 		return
 	}
 
+	setETag(c, category.Version)
+	c.JSON(http.StatusOK, category)
+}
+
+// UpdateCategory handles renaming a category
+// @Summary Rename a category
+// @Description Rename a category (admin only). Products in the category, including those in the trash, are moved to the new name.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID"
+// @Param If-Match header string true "ETag of the category version the change is based on"
+// @Param category body domain.UpdateCategoryRequest true "Category data"
+// @Success 200 {object} domain.Category "Category renamed successfully"
+// @Failure 400 {object} map[string]string "Invalid request"
+// @Failure 404 {object} map[string]string "Category not found"
+// @Failure 409 {object} map[string]string "Category already exists"
+// @Failure 412 {object} map[string]string "Category changed since the given version"
+// @Failure 428 {object} map[string]string "If-Match header missing"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /categories/{id} [put]
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var req domain.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := h.categoryUseCase.UpdateCategory(c.Request.Context(), c.Param("id"), version, req)
+	if err != nil {
+		switch err.Error() {
+		case "category not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "category already exists":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case "version mismatch":
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	setETag(c, category.Version)
 	c.JSON(http.StatusOK, category)
 }
 
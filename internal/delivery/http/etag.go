@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setETag sets the ETag header to the version of a product or category
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// requireIfMatch returns the version in the If-Match header of a request that
+// changes a product or category. Without the header the request is answered
+// with 428, so clients cannot overwrite changes they have not seen, and with
+// 412 when the header is not the ETag of a version.
+func requireIfMatch(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the ETag of the current version is required"})
+		return 0, false
+	}
+
+	// Weak and wildcard tags never match, changes need the exact version
+	tag, quoted := strings.CutPrefix(header, `"`)
+	tag, closed := strings.CutSuffix(tag, `"`)
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if !quoted || !closed || err != nil || version < 0 {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "version mismatch"})
+		return 0, false
+	}
+	return version, true
+}
@@ -25,17 +25,20 @@ func NewInventoryHandler(inventoryUseCase *usecase.InventoryUseCase) *InventoryH
 
 // UpdateStock handles updating product stock
 // @Summary Update product stock
-// @Description Update stock quantity for a specific product
+// @Description Update stock quantity for a specific product, or for one of its variants when variant_id is given
 // @Tags inventory
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Product ID"
+// @Param If-Match header string true "ETag of the product version the stock is based on"
 // @Param request body domain.StockUpdateRequest true "Stock update request"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Router /inventories/{id}/stock [put]
 func (h *InventoryHandler) UpdateStock(c *gin.Context) {
 	id, err := primitive.ObjectIDFromHex(c.Param("id"))
@@ -44,18 +47,31 @@ func (h *InventoryHandler) UpdateStock(c *gin.Context) {
 		return
 	}
 
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
 	var req domain.StockUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = h.inventoryUseCase.UpdateStock(c.Request.Context(), id, req)
+	err = h.inventoryUseCase.UpdateStock(c.Request.Context(), id, version, req)
 	if err != nil {
-		if err.Error() == "product not found" {
+		if err.Error() == "product not found" || err.Error() == "variant not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
+		if err.Error() == "version mismatch" {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "variant is required for products with variants" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -65,7 +81,7 @@ func (h *InventoryHandler) UpdateStock(c *gin.Context) {
 
 // GetLowStockProducts handles getting products with low stock
 // @Summary Get low stock products
-// @Description Get products with stock below threshold
+// @Description Get products with stock below threshold. Products with variants are listed per variant.
 // @Tags inventory
 // @Produce json
 // @Security BearerAuth
@@ -125,7 +141,7 @@ func NewSaleHandler(saleUseCase *usecase.SaleUseCase) *SaleHandler {
 
 // CreateSale handles creating a new sale
 // @Summary Create a new sale
-// @Description Create a new sale transaction
+// @Description Create a new sale transaction. Without a price the sale is made at the price of the product, or of the variant sold.
 // @Tags sales
 // @Accept json
 // @Produce json
@@ -134,6 +150,7 @@ func NewSaleHandler(saleUseCase *usecase.SaleUseCase) *SaleHandler {
 // @Success 201 {object} domain.Sale
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /sales [post]
 func (h *SaleHandler) CreateSale(c *gin.Context) {
@@ -145,14 +162,19 @@ func (h *SaleHandler) CreateSale(c *gin.Context) {
 
 	sale, err := h.saleUseCase.CreateSale(c.Request.Context(), req)
 	if err != nil {
-		if err.Error() == "product not found" {
+		if err.Error() == "product not found" || err.Error() == "variant not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 			return
 		}
-		if err.Error() == "insufficient stock" {
+		if err.Error() == "insufficient stock" || err.Error() == "variant is required for products with variants" ||
+			err.Error() == "variant is not available for sale" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		if err.Error() == "version mismatch" {
+			c.JSON(http.StatusConflict, gin.H{"error": "the product is being changed by other requests, try again"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -206,15 +228,18 @@ func (h *SaleHandler) GetSalesSummary(c *gin.Context) {
 
 // GetSales handles getting sales with filtering
 // @Summary Get sales
-// @Description Get sales with optional filtering by date range
+// @Description Get sales with optional filtering by date range. Passing cursor, empty for the first page, switches to keyset pagination and returns an object with sales and next_cursor.
 // @Tags sales
 // @Produce json
 // @Security BearerAuth
 // @Param from query string false "Start date (YYYY-MM-DD)"
 // @Param to query string false "End date (YYYY-MM-DD)"
 // @Param product_id query string false "Product ID"
+// @Param sort query string false "Sort field: date_sold, total or quantity (default: date_sold)"
+// @Param order query string false "Sort order: asc or desc (default: desc)"
 // @Param page query int false "Page number (default: 1)"
-// @Param limit query int false "Items per page (default: 10)"
+// @Param limit query int false "Items per page (default: 10, max: 100)"
+// @Param cursor query string false "Opaque cursor from next_cursor, empty for the first page"
 // @Success 200 {array} domain.Sale
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
@@ -250,6 +275,9 @@ func (h *SaleHandler) GetSales(c *gin.Context) {
 		}
 	}
 
+	filter.Sort = c.Query("sort")
+	filter.Order = c.Query("order")
+
 	// Pagination
 	filter.Page = 1
 	filter.Limit = 10
@@ -263,10 +291,28 @@ func (h *SaleHandler) GetSales(c *gin.Context) {
 			filter.Limit = limit
 		}
 	}
+	if filter.Limit > domain.MaxPageLimit {
+		filter.Limit = domain.MaxPageLimit
+	}
+
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		filter.Cursor = &cursor
+		sales, nextCursor, err := h.saleUseCase.GetSalesByCursor(c.Request.Context(), filter)
+		if err != nil {
+			handleListError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"sales":       sales,
+			"next_cursor": nextCursor,
+			"limit":       filter.Limit,
+		})
+		return
+	}
 
 	sales, err := h.saleUseCase.GetSalesByFilter(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		handleListError(c, err)
 		return
 	}
 
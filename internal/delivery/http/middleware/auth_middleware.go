@@ -1,28 +1,56 @@
 package middleware
 
 import (
+	"agricultural-equipment-store/internal/domain"
 	"agricultural-equipment-store/internal/usecase"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// AuthMiddleware provides JWT authentication middleware
+// apiKeyHeader is the header API keys are sent in
+const apiKeyHeader = "X-API-Key"
+
+// AuthMiddleware provides JWT and API key authentication middleware
 type AuthMiddleware struct {
-	authUseCase *usecase.AuthUseCase
+	authUseCase   *usecase.AuthUseCase
+	apiKeyUseCase *usecase.APIKeyUseCase
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(authUseCase *usecase.AuthUseCase) *AuthMiddleware {
+func NewAuthMiddleware(authUseCase *usecase.AuthUseCase, apiKeyUseCase *usecase.APIKeyUseCase) *AuthMiddleware {
 	return &AuthMiddleware{
-		authUseCase: authUseCase,
+		authUseCase:   authUseCase,
+		apiKeyUseCase: apiKeyUseCase,
 	}
 }
 
-// RequireAuth middleware that requires authentication
+// RequireAuth middleware that requires authentication with a bearer token or an API key
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Devices and integrations authenticate with an API key
+		if apiKey := c.GetHeader(apiKeyHeader); apiKey != "" {
+			identity, err := m.apiKeyUseCase.Authenticate(c.Request.Context(), apiKey, c.ClientIP())
+			if err != nil {
+				switch err.Error() {
+				case "invalid API key", "API key expired":
+					c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				default:
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify API key"})
+				}
+				c.Abort()
+				return
+			}
+
+			setAPIKeyIdentity(c, identity)
+
+			c.Next()
+			return
+		}
+
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -49,27 +77,63 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens revoked by logout
+		revoked, err := m.authUseCase.IsTokenRevoked(c.Request.Context(), claimString(claims, "jti"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to verify token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+			c.Abort()
+			return
+		}
+
 		// Set user information in context
-		c.Set("user_id", (*claims)["user_id"])
-		c.Set("user_email", (*claims)["email"])
-		c.Set("user_role", (*claims)["role"])
+		setClaims(c, claims)
 
 		c.Next()
 	}
 }
 
-// RequireAdmin middleware that requires admin role
-func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
+// RequirePermission middleware that requires every given permission. It must
+// run after RequireAuth.
+func (m *AuthMiddleware) RequirePermission(permissions ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("user_role")
+		if c.GetBool("mfa_setup_required") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication must be enabled for this account"})
+			c.Abort()
+			return
+		}
+
+		value, exists := c.Get("user_permissions")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user role not found"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "user permissions not found"})
 			c.Abort()
 			return
 		}
 
-		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+		granted, _ := value.([]string)
+		for _, permission := range permissions {
+			if !domain.HasPermission(granted, permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "permission " + permission + " required"})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireVerifiedEmailForPurchase middleware that refuses users with an
+// unverified email when purchases require a verified email. It must run after
+// RequireAuth.
+func (m *AuthMiddleware) RequireVerifiedEmailForPurchase() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m.authUseCase.RequiresVerifiedEmailForPurchase() && !c.GetBool("email_verified") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "email address must be verified"})
 			c.Abort()
 			return
 		}
@@ -81,6 +145,15 @@ func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 // OptionalAuth middleware that allows both authenticated and anonymous access
 func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Treat invalid API keys as anonymous
+		if apiKey := c.GetHeader(apiKeyHeader); apiKey != "" {
+			if identity, err := m.apiKeyUseCase.Authenticate(c.Request.Context(), apiKey, c.ClientIP()); err == nil {
+				setAPIKeyIdentity(c, identity)
+			}
+			c.Next()
+			return
+		}
+
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -104,11 +177,82 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 			return
 		}
 
+		// Treat revoked tokens as anonymous
+		revoked, err := m.authUseCase.IsTokenRevoked(c.Request.Context(), claimString(claims, "jti"))
+		if err != nil || revoked {
+			c.Next()
+			return
+		}
+
 		// Set user information in context
-		c.Set("user_id", (*claims)["user_id"])
-		c.Set("user_email", (*claims)["email"])
-		c.Set("user_role", (*claims)["role"])
+		setClaims(c, claims)
 
 		c.Next()
 	}
 }
+
+// setClaims stores the token claims in the request context
+func setClaims(c *gin.Context, claims *jwt.MapClaims) {
+	c.Set("user_id", (*claims)["user_id"])
+	c.Set("user_email", (*claims)["email"])
+	c.Set("user_role", (*claims)["role"])
+	c.Set("user_permissions", claimStrings(claims, "permissions"))
+	c.Set("token_id", claimString(claims, "jti"))
+
+	if emailVerified, _ := (*claims)["email_verified"].(bool); emailVerified {
+		c.Set("email_verified", true)
+	}
+
+	if mfaSetupRequired, _ := (*claims)["mfa_setup_required"].(bool); mfaSetupRequired {
+		c.Set("mfa_setup_required", true)
+	}
+
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		c.Set("token_expires_at", exp.Time)
+	}
+
+	setActor(c, claimString(claims, "user_id"), claimString(claims, "email"))
+}
+
+// setAPIKeyIdentity stores the identity of an API key in the request context,
+// using the same keys as a bearer token
+func setAPIKeyIdentity(c *gin.Context, identity *domain.APIKeyIdentity) {
+	c.Set("user_id", identity.User.ID.Hex())
+	c.Set("user_email", identity.User.Email)
+	c.Set("user_role", identity.User.Role)
+	c.Set("user_permissions", identity.Permissions)
+	c.Set("api_key_id", identity.Key.ID.Hex())
+	c.Set("email_verified", identity.User.EmailVerifiedAt != nil)
+
+	setActor(c, identity.User.ID.Hex(), identity.User.Email)
+}
+
+// setActor stores the authenticated user in the request's context.Context,
+// where use cases read it to record who made a change
+func setActor(c *gin.Context, userID, email string) {
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return
+	}
+	actor := domain.Actor{UserID: id, Email: email}
+	c.Request = c.Request.WithContext(domain.ContextWithActor(c.Request.Context(), actor))
+}
+
+// claimString returns a string claim or an empty string when it is missing
+func claimString(claims *jwt.MapClaims, key string) string {
+	value, _ := (*claims)[key].(string)
+	return value
+}
+
+// claimStrings returns a string list claim or an empty list when it is missing
+func claimStrings(claims *jwt.MapClaims, key string) []string {
+	values, _ := (*claims)[key].([]interface{})
+
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/usecase"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie holds the state of the login started in a browser
+const oidcStateCookie = "oidc_state"
+
+// OIDCHandler handles login through an OpenID Connect identity provider
+type OIDCHandler struct {
+	oidcUseCase *usecase.OIDCUseCase
+}
+
+// NewOIDCHandler creates a new OpenID Connect handler
+func NewOIDCHandler(oidcUseCase *usecase.OIDCUseCase) *OIDCHandler {
+	return &OIDCHandler{
+		oidcUseCase: oidcUseCase,
+	}
+}
+
+// Authorize handles starting a login at the identity provider
+// @Summary Start identity provider login
+// @Description Start an authorization code login with PKCE. Send the browser to authorization_url; the provider redirects back to the frontend with code and state, which are posted to /auth/oidc/callback. The state is also set as an HttpOnly cookie that the callback must send.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} domain.OIDCAuthorizationResponse
+// @Failure 502 {object} map[string]string
+// @Router /auth/oidc/authorize [get]
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	response, err := h.oidcUseCase.StartLogin(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, response.State, int(time.Until(response.ExpiresAt).Seconds()), "/api/auth/oidc", "", isSecureRequest(c), true)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Callback handles completing a login at the identity provider
+// @Summary Complete identity provider login
+// @Description Exchange the code and state from the identity provider redirect for an access token and a refresh token. The oidc_state cookie set by /auth/oidc/authorize must be sent. Users are linked by email or provisioned on first login, and the role of provisioned users follows their groups at the provider. Users with two-factor authentication get an MFA challenge (domain.MFAChallengeResponse) instead.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body domain.OIDCCallbackRequest true "Callback request"
+// @Success 200 {object} domain.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /auth/oidc/callback [post]
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	var req domain.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Browser, _ = c.Cookie(oidcStateCookie)
+	req.IPAddress = c.ClientIP()
+	req.UserAgent = c.Request.UserAgent()
+
+	// The state is single use, whether or not the login succeeds
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", isSecureRequest(c), true)
+
+	response, challenge, err := h.oidcUseCase.CompleteLogin(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if challenge != nil {
+		c.JSON(http.StatusOK, challenge)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// handleError maps identity provider login errors to HTTP responses
+func (h *OIDCHandler) handleError(c *gin.Context, err error) {
+	var providerErr *usecase.IdentityProviderError
+	if errors.As(err, &providerErr) {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err.Error() {
+	case "invalid or expired login state", "login was not started in this browser":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "user account is inactive":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case "email is not verified by the identity provider", "email domain is not allowed", "no account exists for this email":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// isSecureRequest reports whether a request reached the API, or the proxy in
+// front of it, over HTTPS
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
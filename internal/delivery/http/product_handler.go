@@ -4,8 +4,12 @@ import (
 	"agricultural-equipment-store/internal/domain"
 	"agricultural-equipment-store/internal/usecase"
 	"agricultural-equipment-store/internal/utils"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -14,6 +18,19 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// maxImportFileSize is the largest product import file accepted
+const maxImportFileSize = 10 << 20
+
+// maxPatchSize is the largest product patch accepted
+const maxPatchSize = 1 << 20
+
+// exportContentTypes maps the product export formats to their content types
+var exportContentTypes = map[string]string{
+	domain.ExportFormatCSV:  "text/csv",
+	domain.ExportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	domain.ExportFormatJSON: "application/json",
+}
+
 // ProductHandler handles product endpoints
 type ProductHandler struct {
 	productUseCase *usecase.ProductUseCase
@@ -36,6 +53,8 @@ func NewProductHandler(productUseCase *usecase.ProductUseCase) *ProductHandler {
 // @Produce json
 // @Security BearerAuth
 // @Param request body domain.CreateProductRequest true "Product creation request (JSON)"
+// @Param sku formData string false "Product SKU (Form)"
+// @Param barcodes formData string false "Comma-separated barcodes (Form)"
 // @Param name formData string true "Product name (Form)"
 // @Param description formData string false "Product description (Form)"
 // @Param price formData number true "Product price (Form)"
@@ -43,6 +62,8 @@ func NewProductHandler(productUseCase *usecase.ProductUseCase) *ProductHandler {
 // @Param brand formData string false "Product brand (Form)"
 // @Param stock formData integer true "Product stock (Form)"
 // @Param image_urls formData string false "Comma-separated image URLs (Form)"
+// @Param variants formData string false "Variants as a JSON array (Form)"
+// @Param specs formData string false "Specs as a JSON array of key and value (Form)"
 // @Param images formData file false "Product images (Form, multiple files allowed)"
 // @Success 201 {object} domain.Product
 // @Failure 400 {object} map[string]string
@@ -69,10 +90,11 @@ func (h *ProductHandler) createProductWithJSON(c *gin.Context) {
 
 	product, err := h.productUseCase.CreateProduct(c.Request.Context(), req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		handleProductError(c, err)
 		return
 	}
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusCreated, product)
 }
 
@@ -87,6 +109,7 @@ func (h *ProductHandler) createProductWithFiles(c *gin.Context) {
 
 	// Extract basic product data
 	req := domain.CreateProductRequest{
+		SKU:         c.PostForm("sku"),
 		Name:        c.PostForm("name"),
 		Description: c.PostForm("description"),
 		Category:    c.PostForm("category"),
@@ -113,12 +136,33 @@ func (h *ProductHandler) createProductWithFiles(c *gin.Context) {
 		}
 	}
 
+	// Parse barcodes (comma-separated, types are detected)
+	if barcodes := c.PostForm("barcodes"); barcodes != "" {
+		req.Barcodes = parseBarcodes(barcodes)
+	}
+
+	// Parse variants (JSON array)
+	if variantsStr := c.PostForm("variants"); variantsStr != "" {
+		if err := json.Unmarshal([]byte(variantsStr), &req.Variants); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variants format"})
+			return
+		}
+	}
+
+	// Parse specs (JSON array)
+	if specsStr := c.PostForm("specs"); specsStr != "" {
+		if err := json.Unmarshal([]byte(specsStr), &req.Specs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid specs format"})
+			return
+		}
+	}
+
 	// Validate required fields
 	if req.Name == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Product name is required"})
 		return
 	}
-	if req.Price <= 0 {
+	if req.Price <= 0 && len(req.Variants) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Product price must be greater than 0"})
 		return
 	}
@@ -180,10 +224,11 @@ func (h *ProductHandler) createProductWithFiles(c *gin.Context) {
 				h.uploadConfig.DeleteFile(img.FilePath)
 			}
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		handleProductError(c, err)
 		return
 	}
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusCreated, product)
 }
 
@@ -194,6 +239,7 @@ func (h *ProductHandler) createProductWithFiles(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Product ID"
 // @Success 200 {object} domain.Product
+// @Header 200 {string} ETag "Product version, sent as If-Match to change the product"
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /products/{id} [get]
@@ -215,41 +261,65 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 		return
 	}
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, product)
 }
 
+// LookupProduct handles finding a product by a scanned SKU or barcode
+// @Summary Look up product by code
+// @Description Find the product, and variant, a SKU or barcode belongs to. UPC-A codes match with or without a leading zero.
+// @Tags products
+// @Produce json
+// @Param code query string true "SKU or barcode"
+// @Success 200 {object} domain.ProductLookupResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /products/lookup [get]
+func (h *ProductHandler) LookupProduct(c *gin.Context) {
+	result, err := h.productUseCase.LookupProduct(c.Request.Context(), c.Query("code"))
+	if err != nil {
+		switch err.Error() {
+		case "code is required":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // GetProducts handles getting products with filtering and pagination
 // @Summary Get products
-// @Description Get products with optional filtering and pagination
+// @Description Get products with optional filtering and pagination. Passing cursor, empty for the first page, switches to keyset pagination and returns next_cursor instead of page totals.
 // @Tags products
 // @Produce json
 // @Param category query string false "Category filter"
 // @Param brand query string false "Brand filter"
 // @Param min_price query number false "Minimum price filter"
 // @Param max_price query number false "Maximum price filter"
-// @Param search query string false "Search in name and description"
+// @Param search query string false "Full-text search in name, brand and description, ranked by relevance"
+// @Param variant.{attribute} query string false "Variant attribute value, e.g. variant.tank_size=20L"
+// @Param {spec} query string false "Spec attribute value, comma-separated for several, e.g. fuel=diesel"
+// @Param {spec}_min query number false "Lowest value of a number spec attribute, e.g. hp_min=30"
+// @Param {spec}_max query number false "Highest value of a number spec attribute, e.g. hp_max=60"
+// @Param sort query string false "Sort field: price, name, stock, created_at, popularity or relevance (default relevance when searching, otherwise created_at)"
+// @Param order query string false "Sort order: asc or desc (default asc for name, otherwise desc)"
 // @Param page query int false "Page number (default 1)"
-// @Param limit query int false "Items per page (default 10)"
+// @Param limit query int false "Items per page (default 10, max 100)"
+// @Param cursor query string false "Opaque cursor from next_cursor, empty for the first page"
+// @Param facets query bool false "Include category, brand, price range and availability counts (default true)"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Router /products [get]
 func (h *ProductHandler) GetProducts(c *gin.Context) {
-	// Parse query parameters
-	filter := domain.ProductFilter{}
-
-	filter.Category = c.Query("category")
-	filter.Brand = c.Query("brand")
-	filter.Search = c.Query("search")
-
-	if minPriceStr := c.Query("min_price"); minPriceStr != "" {
-		if minPrice, err := strconv.ParseFloat(minPriceStr, 64); err == nil {
-			filter.MinPrice = minPrice
-		}
-	}
-
-	if maxPriceStr := c.Query("max_price"); maxPriceStr != "" {
-		if maxPrice, err := strconv.ParseFloat(maxPriceStr, 64); err == nil {
-			filter.MaxPrice = maxPrice
-		}
+	filter, err := h.parseProductFilter(c)
+	if err != nil {
+		handleProductError(c, err)
+		return
 	}
 
 	if pageStr := c.Query("page"); pageStr != "" {
@@ -263,14 +333,18 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 			filter.Limit = limit
 		}
 	}
+	if filter.Limit > domain.MaxPageLimit {
+		filter.Limit = domain.MaxPageLimit
+	}
 
-	// Set default active filter to true for public endpoint
-	isActive := true
-	filter.IsActive = &isActive
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		h.getProductsByCursor(c, filter, cursor)
+		return
+	}
 
 	products, count, err := h.productUseCase.GetProducts(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		handleListError(c, err)
 		return
 	}
 
@@ -290,6 +364,86 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 		"total_pages": totalPages,
 	}
 
+	// Facet counts for the filter sidebar, skipped with facets=false
+	if c.Query("facets") != "false" {
+		facets, err := h.productUseCase.GetProductFacets(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response["facets"] = facets
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// parseProductFilter reads the filter, sort and spec query parameters of a
+// public product list
+func (h *ProductHandler) parseProductFilter(c *gin.Context) (domain.ProductFilter, error) {
+	filter := domain.ProductFilter{}
+
+	filter.Category = c.Query("category")
+	filter.Brand = c.Query("brand")
+	filter.Search = c.Query("search")
+
+	if minPriceStr := c.Query("min_price"); minPriceStr != "" {
+		if minPrice, err := strconv.ParseFloat(minPriceStr, 64); err == nil {
+			filter.MinPrice = minPrice
+		}
+	}
+
+	if maxPriceStr := c.Query("max_price"); maxPriceStr != "" {
+		if maxPrice, err := strconv.ParseFloat(maxPriceStr, 64); err == nil {
+			filter.MaxPrice = maxPrice
+		}
+	}
+
+	filter.VariantAttributes = parseVariantAttributes(c)
+
+	specs, err := h.productUseCase.ParseSpecFilters(c.Request.Context(), c.Request.URL.Query())
+	if err != nil {
+		return filter, err
+	}
+	filter.Specs = specs
+	filter.Sort = c.Query("sort")
+	filter.Order = c.Query("order")
+
+	// Set default active filter to true for public endpoint
+	isActive := true
+	filter.IsActive = &isActive
+
+	return filter, nil
+}
+
+// getProductsByCursor responds with a keyset paginated product list
+func (h *ProductHandler) getProductsByCursor(c *gin.Context, filter domain.ProductFilter, cursor string) {
+	filter.Cursor = &cursor
+
+	products, nextCursor, err := h.productUseCase.GetProductsByCursor(c.Request.Context(), filter)
+	if err != nil {
+		handleListError(c, err)
+		return
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+
+	response := gin.H{
+		"products":    products,
+		"next_cursor": nextCursor,
+		"limit":       filter.Limit,
+	}
+
+	if c.Query("facets") != "false" {
+		facets, err := h.productUseCase.GetProductFacets(c.Request.Context(), filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		response["facets"] = facets
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
@@ -301,7 +455,10 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Product ID"
+// @Param If-Match header string true "ETag of the product version the update is based on"
 // @Param request body domain.UpdateProductRequest true "Product update request (JSON)"
+// @Param sku formData string false "Product SKU (Form)"
+// @Param barcodes formData string false "Comma-separated barcodes (Form)"
 // @Param name formData string false "Product name (Form)"
 // @Param description formData string false "Product description (Form)"
 // @Param price formData number false "Product price (Form)"
@@ -310,25 +467,93 @@ func (h *ProductHandler) GetProducts(c *gin.Context) {
 // @Param stock formData integer false "Product stock (Form)"
 // @Param is_active formData boolean false "Product active status (Form)"
 // @Param image_urls formData string false "Comma-separated image URLs (Form)"
+// @Param variants formData string false "Variants as a JSON array (Form)"
+// @Param specs formData string false "Specs as a JSON array of key and value (Form)"
 // @Param images formData file false "Product images (Form, multiple files allowed)"
 // @Success 200 {object} domain.Product
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
 // @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 428 {object} map[string]string
 // @Router /products/{id} [put]
 func (h *ProductHandler) UpdateProduct(c *gin.Context) {
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
 	contentType := c.GetHeader("Content-Type")
 
 	if strings.Contains(contentType, "multipart/form-data") {
-		h.updateProductWithFiles(c)
+		h.updateProductWithFiles(c, version)
 	} else {
-		h.updateProductWithJSON(c)
+		h.updateProductWithJSON(c, version)
+	}
+}
+
+// PatchProduct handles changing only the given fields of a product
+// @Summary Patch a product
+// @Description Change only the fields a patch gives (admin only). Send a JSON Merge Patch (RFC 7396) as application/merge-patch+json or application/json, e.g. {"description": null} clears the description, or a JSON Patch (RFC 6902) as application/json-patch+json. Patches apply to the fields of domain.ProductPatchDocument.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param If-Match header string true "ETag of the product version the patch is based on"
+// @Param request body domain.ProductPatchDocument true "Merge patch with the fields to change, or a JSON Patch array"
+// @Success 200 {object} domain.Product
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Failure 428 {object} map[string]string
+// @Router /products/{id} [patch]
+func (h *ProductHandler) PatchProduct(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	version, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var patchType string
+	switch c.ContentType() {
+	case "application/merge-patch+json", "application/json":
+		patchType = domain.PatchTypeMerge
+	case "application/json-patch+json":
+		patchType = domain.PatchTypeJSON
+	default:
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/merge-patch+json or application/json-patch+json"})
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read patch"})
+		return
 	}
+
+	product, err := h.productUseCase.PatchProduct(c.Request.Context(), id, version, patchType, patch)
+	if err != nil {
+		handleProductError(c, err)
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, product)
 }
 
 // updateProductWithJSON handles JSON-based product updates
-func (h *ProductHandler) updateProductWithJSON(c *gin.Context) {
+func (h *ProductHandler) updateProductWithJSON(c *gin.Context, version int64) {
 	idStr := c.Param("id")
 	id, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -342,21 +567,18 @@ func (h *ProductHandler) updateProductWithJSON(c *gin.Context) {
 		return
 	}
 
-	product, err := h.productUseCase.UpdateProduct(c.Request.Context(), id, req)
+	product, err := h.productUseCase.UpdateProduct(c.Request.Context(), id, version, req)
 	if err != nil {
-		if err.Error() == "product not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		handleProductError(c, err)
 		return
 	}
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, product)
 }
 
 // updateProductWithFiles handles multipart form-based product updates with file uploads
-func (h *ProductHandler) updateProductWithFiles(c *gin.Context) {
+func (h *ProductHandler) updateProductWithFiles(c *gin.Context, version int64) {
 	idStr := c.Param("id")
 	id, err := primitive.ObjectIDFromHex(idStr)
 	if err != nil {
@@ -373,6 +595,7 @@ func (h *ProductHandler) updateProductWithFiles(c *gin.Context) {
 
 	// Extract product data
 	req := domain.UpdateProductRequest{
+		SKU:         c.PostForm("sku"),
 		Name:        c.PostForm("name"),
 		Description: c.PostForm("description"),
 		Category:    c.PostForm("category"),
@@ -389,13 +612,34 @@ func (h *ProductHandler) updateProductWithFiles(c *gin.Context) {
 		}
 	}
 
-	// Parse stock
+	// Parse stock, the current stock is kept when it is left out
 	if stockStr := c.PostForm("stock"); stockStr != "" {
-		if stock, err := strconv.Atoi(stockStr); err != nil {
+		if stock, err := strconv.Atoi(stockStr); err != nil || stock < 0 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stock format"})
 			return
 		} else {
-			req.Stock = stock
+			req.Stock = &stock
+		}
+	}
+
+	// Parse barcodes (comma-separated, types are detected)
+	if barcodes := c.PostForm("barcodes"); barcodes != "" {
+		req.Barcodes = parseBarcodes(barcodes)
+	}
+
+	// Parse variants (JSON array)
+	if variantsStr := c.PostForm("variants"); variantsStr != "" {
+		if err := json.Unmarshal([]byte(variantsStr), &req.Variants); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variants format"})
+			return
+		}
+	}
+
+	// Parse specs (JSON array)
+	if specsStr := c.PostForm("specs"); specsStr != "" {
+		if err := json.Unmarshal([]byte(specsStr), &req.Specs); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid specs format"})
+			return
 		}
 	}
 
@@ -455,7 +699,7 @@ func (h *ProductHandler) updateProductWithFiles(c *gin.Context) {
 	}
 
 	// Update product with enhanced request
-	product, err := h.productUseCase.UpdateProductWithImages(c.Request.Context(), id, req, uploadedImages)
+	product, err := h.productUseCase.UpdateProductWithImages(c.Request.Context(), id, version, req, uploadedImages)
 	if err != nil {
 		// Clean up uploaded files on error
 		for _, img := range uploadedImages {
@@ -463,20 +707,17 @@ func (h *ProductHandler) updateProductWithFiles(c *gin.Context) {
 				h.uploadConfig.DeleteFile(img.FilePath)
 			}
 		}
-		if err.Error() == "product not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		handleProductError(c, err)
 		return
 	}
 
+	setETag(c, product.Version)
 	c.JSON(http.StatusOK, product)
 }
 
 // DeleteProduct handles deleting a product
 // @Summary Delete product
-// @Description Delete a product (admin only)
+// @Description Move a product to the trash (admin only). It can be restored until it is purged after the trash retention period.
 // @Tags products
 // @Produce json
 // @Security BearerAuth
@@ -495,7 +736,8 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	err = h.productUseCase.DeleteProduct(c.Request.Context(), id)
+	deletedBy, _ := currentUserID(c)
+	err = h.productUseCase.DeleteProduct(c.Request.Context(), id, deletedBy)
 	if err != nil {
 		if err.Error() == "product not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -505,5 +747,677 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "product deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "product moved to trash"})
+}
+
+// GetDeletedProducts handles listing the products in the trash
+// @Summary Get deleted products
+// @Description Get the products in the trash, most recently deleted first (admin only)
+// @Tags products
+// @Produce json
+// @Security BearerAuth
+// @Param category query string false "Category filter"
+// @Param brand query string false "Brand filter"
+// @Param search query string false "Full-text search in name, brand and description"
+// @Param sort query string false "Sort field: price, name, stock, created_at or popularity (default deletion time)"
+// @Param order query string false "Sort order: asc or desc"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 10, max 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /products/trash [get]
+func (h *ProductHandler) GetDeletedProducts(c *gin.Context) {
+	filter := domain.ProductFilter{
+		Category: c.Query("category"),
+		Brand:    c.Query("brand"),
+		Search:   c.Query("search"),
+		Sort:     c.Query("sort"),
+		Order:    c.Query("order"),
+	}
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil {
+			filter.Page = page
+		}
+	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			filter.Limit = limit
+		}
+	}
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	} else if filter.Limit > domain.MaxPageLimit {
+		filter.Limit = domain.MaxPageLimit
+	}
+
+	products, count, err := h.productUseCase.GetDeletedProducts(c.Request.Context(), filter)
+	if err != nil {
+		handleListError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"products":    products,
+		"total":       count,
+		"page":        filter.Page,
+		"limit":       filter.Limit,
+		"total_pages": (count + int64(filter.Limit) - 1) / int64(filter.Limit),
+	})
+}
+
+// RestoreProduct handles moving a product out of the trash
+// @Summary Restore product
+// @Description Restore a product from the trash (admin only)
+// @Tags products
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 200 {object} domain.Product
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /products/{id}/restore [post]
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	product, err := h.productUseCase.RestoreProduct(c.Request.Context(), id)
+	if err != nil {
+		if err.Error() == "product not found in trash" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	setETag(c, product.Version)
+	c.JSON(http.StatusOK, product)
+}
+
+// GetProductHistory handles listing the changes made to a product
+// @Summary Get product history
+// @Description Get the revisions of a product with the changed fields, who changed them and when, newest first (admin only)
+// @Tags products
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 10, max 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /products/{id}/history [get]
+func (h *ProductHandler) GetProductHistory(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	} else if limit > domain.MaxPageLimit {
+		limit = domain.MaxPageLimit
+	}
+
+	revisions, count, err := h.productUseCase.GetProductHistory(c.Request.Context(), id, page, limit)
+	if err != nil {
+		handleProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions":   revisions,
+		"total":       count,
+		"page":        page,
+		"limit":       limit,
+		"total_pages": (count + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// ImportProducts handles creating and updating products from a spreadsheet
+// @Summary Import products
+// @Description Create and update products from a CSV or XLSX file whose first row names the columns: sku, name, description, price, category, brand, stock, is_active, barcodes, image_url, image_urls, variants (a JSON array) and spec.<key>. Rows update the product with the same SKU, or the same name when they have no SKU, and create the others. Every row is validated; rows with errors are skipped and reported (admin only).
+// @Tags products
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV or XLSX file"
+// @Param dry_run formData bool false "Validate the rows without saving them"
+// @Success 200 {object} domain.ProductImportResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /products/import [post]
+func (h *ProductHandler) ImportProducts(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("import file is larger than %d MB", maxImportFileSize>>20)})
+		return
+	}
+
+	dryRun := false
+	if value := c.PostForm("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run value"})
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read import file"})
+		return
+	}
+	defer file.Close()
+
+	var table [][]string
+	switch strings.ToLower(filepath.Ext(fileHeader.Filename)) {
+	case ".csv":
+		table, err = utils.ReadCSV(file)
+	case ".xlsx":
+		table, err = utils.ReadXLSX(file, fileHeader.Size, domain.MaxImportRows)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "import file must be a .csv or .xlsx file"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.productUseCase.ImportProducts(c.Request.Context(), table, dryRun)
+	if err != nil {
+		var importErr *usecase.InvalidImportError
+		if errors.As(err, &importErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// BulkUpdateProducts handles changing many products at once
+// @Summary Bulk update products
+// @Description Adjust the price, set the active status or change the category or brand of the products given by ids or matching filter (admin only). Prices change by a percent or an amount and are rounded to a multiple of round_to; products with variants have their variant prices adjusted. With preview the changes are returned without saving them. Products that could not be saved are counted in failed and have an error on their change.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.BulkProductRequest true "Products and changes"
+// @Success 200 {object} domain.BulkProductResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /products/bulk [post]
+func (h *ProductHandler) BulkUpdateProducts(c *gin.Context) {
+	var req domain.BulkProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.productUseCase.BulkUpdateProducts(c.Request.Context(), req)
+	if err != nil {
+		handleBulkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportProducts handles downloading the catalog
+// @Summary Export products
+// @Description Download every product matching the same filters as the product list, without pagination, as CSV, XLSX or JSON. CSV and XLSX have one column per field and per spec attribute, named like the import columns; JSON has the full products. The file is streamed as it is read.
+// @Tags products
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,json
+// @Security BearerAuth
+// @Param format query string false "Export format: csv, xlsx or json" default(csv)
+// @Param category query string false "Category filter"
+// @Param brand query string false "Brand filter"
+// @Param min_price query number false "Minimum price filter"
+// @Param max_price query number false "Maximum price filter"
+// @Param search query string false "Full-text search in name, brand and description"
+// @Param variant.{attribute} query string false "Variant attribute value, e.g. variant.tank_size=20L"
+// @Param {spec} query string false "Spec attribute value, comma-separated for several, e.g. fuel=diesel"
+// @Param {spec}_min query number false "Lowest value of a number spec attribute, e.g. hp_min=30"
+// @Param {spec}_max query number false "Highest value of a number spec attribute, e.g. hp_max=60"
+// @Param sort query string false "Sort field: price, name, stock, created_at, popularity or relevance"
+// @Param order query string false "Sort order: asc or desc"
+// @Success 200 {file} file "Product export"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /products/export [get]
+func (h *ProductHandler) ExportProducts(c *gin.Context) {
+	filter, err := h.parseProductFilter(c)
+	if err != nil {
+		handleProductError(c, err)
+		return
+	}
+
+	format := c.DefaultQuery("format", domain.ExportFormatCSV)
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format"})
+		return
+	}
+
+	// Large catalogs take longer to send than the server write timeout allows
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", "attachment; filename=products_export."+format)
+
+	err = h.productUseCase.ExportProducts(c.Request.Context(), filter, format, c.Writer)
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		// The status was sent with the first product, so the download is cut short
+		_ = c.Error(err)
+		c.Abort()
+		return
+	}
+	c.Writer.Header().Del("Content-Disposition")
+	handleListError(c, err)
+}
+
+// GetPriceHistory handles getting the price history of a product for charting
+// @Summary Get product price history
+// @Description Get the price series of a product and each of its variants. Every point is the price from its date until the next point; the first point is the price in effect at from.
+// @Tags products
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param from query string false "Start date (YYYY-MM-DD)"
+// @Param to query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} domain.PriceHistory
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /products/{id}/price-history [get]
+func (h *ProductHandler) GetPriceHistory(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	var from, to time.Time
+	if fromStr := c.Query("from"); fromStr != "" {
+		from, err = time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from date format (use YYYY-MM-DD)"})
+			return
+		}
+	}
+	if toStr := c.Query("to"); toStr != "" {
+		to, err = time.Parse("2006-01-02", toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to date format (use YYYY-MM-DD)"})
+			return
+		}
+		// Set to end of day
+		to = to.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+
+	history, err := h.productUseCase.GetPriceHistory(c.Request.Context(), id, from, to)
+	if err != nil {
+		handleProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// GetCompatibleParts handles listing the parts that fit a machine
+// @Summary Get compatible parts
+// @Description Get the parts that fit a machine product, such as the filters, belts and blades for a tractor model, with the years and serial numbers each part is limited to. Give the machine's model year and serial number to leave out parts that do not fit it.
+// @Tags products
+// @Produce json
+// @Param id path string true "Machine product ID"
+// @Param year query int false "Model year of the machine"
+// @Param serial query string false "Serial number of the machine"
+// @Param category query string false "Part category, e.g. Filters"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /products/{id}/compatible-parts [get]
+func (h *ProductHandler) GetCompatibleParts(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	filter := domain.CompatibilityFilter{
+		Serial:   c.Query("serial"),
+		Category: c.Query("category"),
+	}
+	if yearStr := c.Query("year"); yearStr != "" {
+		filter.Year, err = strconv.Atoi(yearStr)
+		if err != nil || filter.Year <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid year"})
+			return
+		}
+	}
+
+	parts, err := h.productUseCase.GetCompatibleParts(c.Request.Context(), id, filter)
+	if err != nil {
+		handleProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"parts": parts})
+}
+
+// GetPartFits handles listing the machines a part fits
+// @Summary Get machines a part fits
+// @Description Get the machine products a part fits, with the years and serial numbers it is limited to for each
+// @Tags products
+// @Produce json
+// @Param id path string true "Part product ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /products/{id}/fits [get]
+func (h *ProductHandler) GetPartFits(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product ID"})
+		return
+	}
+
+	machines, err := h.productUseCase.GetPartFits(c.Request.Context(), id)
+	if err != nil {
+		handleProductError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"machines": machines})
+}
+
+// BulkUpdateCompatibility handles maintaining part compatibility mappings
+// @Summary Bulk update part compatibility
+// @Description Set and remove which parts fit which machines (admin only). Setting a part and machine that are already mapped replaces the years, serial numbers and notes of the mapping. Every mapping is checked before any is saved.
+// @Tags products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.CompatibilityBulkRequest true "Mappings to set and remove"
+// @Success 200 {object} domain.CompatibilityBulkResult
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /products/compatibility [post]
+func (h *ProductHandler) BulkUpdateCompatibility(c *gin.Context) {
+	var req domain.CompatibilityBulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.productUseCase.BulkUpdateCompatibility(c.Request.Context(), req)
+	if err != nil {
+		var compatibilityErr *usecase.InvalidCompatibilityError
+		switch {
+		case errors.As(err, &compatibilityErr):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case err.Error() == "no changes requested", err.Error() == "too many mappings":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetSpecAttributes handles listing the spec attributes products can have
+// @Summary Get spec attributes
+// @Description Get the technical specification attributes products can have and filter on
+// @Tags spec-attributes
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /spec-attributes [get]
+func (h *ProductHandler) GetSpecAttributes(c *gin.Context) {
+	attributes, err := h.productUseCase.GetSpecAttributes(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"spec_attributes": attributes})
+}
+
+// CreateSpecAttribute handles creating a spec attribute
+// @Summary Create spec attribute
+// @Description Create a number, enum or boolean technical specification attribute (admin only)
+// @Tags spec-attributes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.CreateSpecAttributeRequest true "Spec attribute"
+// @Success 201 {object} domain.SpecAttribute
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /spec-attributes [post]
+func (h *ProductHandler) CreateSpecAttribute(c *gin.Context) {
+	var req domain.CreateSpecAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attribute, err := h.productUseCase.CreateSpecAttribute(c.Request.Context(), req)
+	if err != nil {
+		handleSpecAttributeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, attribute)
+}
+
+// UpdateSpecAttribute handles updating a spec attribute
+// @Summary Update spec attribute
+// @Description Update the name or enum values of a spec attribute (admin only). Values still used by products cannot be removed.
+// @Tags spec-attributes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Spec attribute ID"
+// @Param request body domain.UpdateSpecAttributeRequest true "Spec attribute update"
+// @Success 200 {object} domain.SpecAttribute
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /spec-attributes/{id} [put]
+func (h *ProductHandler) UpdateSpecAttribute(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid spec attribute ID"})
+		return
+	}
+
+	var req domain.UpdateSpecAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attribute, err := h.productUseCase.UpdateSpecAttribute(c.Request.Context(), id, req)
+	if err != nil {
+		handleSpecAttributeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attribute)
+}
+
+// DeleteSpecAttribute handles deleting a spec attribute
+// @Summary Delete spec attribute
+// @Description Delete a spec attribute no product uses (admin only)
+// @Tags spec-attributes
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Spec attribute ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /spec-attributes/{id} [delete]
+func (h *ProductHandler) DeleteSpecAttribute(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid spec attribute ID"})
+		return
+	}
+
+	if err := h.productUseCase.DeleteSpecAttribute(c.Request.Context(), id); err != nil {
+		handleSpecAttributeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "spec attribute deleted successfully"})
+}
+
+// parseVariantAttributes reads variant.<attribute>=value query parameters.
+// Attribute names are limited to letters, digits, '_' and '-'.
+func parseVariantAttributes(c *gin.Context) map[string]string {
+	var attributes map[string]string
+	for key, values := range c.Request.URL.Query() {
+		name, ok := strings.CutPrefix(key, "variant.")
+		if !ok || !isAttributeName(name) || len(values) == 0 || values[0] == "" {
+			continue
+		}
+		if attributes == nil {
+			attributes = make(map[string]string)
+		}
+		attributes[name] = values[0]
+	}
+	return attributes
+}
+
+// isAttributeName reports whether a name can be used as an attribute key
+func isAttributeName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseBarcodes splits a comma-separated list of barcodes
+func parseBarcodes(value string) []domain.ProductBarcode {
+	barcodes := []domain.ProductBarcode{}
+	for _, code := range strings.Split(value, ",") {
+		if code = strings.TrimSpace(code); code != "" {
+			barcodes = append(barcodes, domain.ProductBarcode{Code: code})
+		}
+	}
+	return barcodes
+}
+
+// handleProductError writes the response for product create and update errors
+func handleProductError(c *gin.Context, err error) {
+	var barcodeErr *usecase.InvalidBarcodeError
+	var specErr *usecase.InvalidSpecError
+	var patchErr *usecase.InvalidPatchError
+	if errors.As(err, &barcodeErr) || errors.As(err, &specErr) || errors.As(err, &patchErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err.Error() {
+	case "product not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "variant SKU is required", "variant price must be greater than 0",
+		"variant stock cannot be negative", "duplicate variant SKU", "variant not found",
+		"duplicate SKU", "duplicate barcode",
+		"name is required", "category is required", "price must be greater than 0", "stock cannot be negative",
+		"price and stock of a product with variants are set on its variants":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "SKU already exists", "barcode already exists", "patch test failed":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "version mismatch":
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// handleListError maps errors of sorted and paginated lists to HTTP responses
+func handleListError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "invalid sort field", "invalid sort order", "invalid cursor",
+		"relevance sorting requires a search", "cursor pagination does not support relevance sorting":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// handleBulkError maps bulk product update errors to HTTP responses
+func handleBulkError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "either ids or filter is required", "no changes requested", "invalid product ID",
+		"too many products, narrow the filter", "price adjustment requires either percent or amount",
+		"price percent must be greater than -100", "price rounding must be at least 0.01",
+		"adjusted price must be greater than 0":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "product not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		handleListError(c, err)
+	}
+}
+
+// handleSpecAttributeError maps spec attribute errors to HTTP responses
+func handleSpecAttributeError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "invalid spec attribute key", "only enum attributes have values",
+		"enum values cannot be empty or contain commas", "duplicate enum value", "enum attributes require values":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "spec attribute not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "spec attribute already exists", "spec attribute is in use", "spec value is in use":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/usecase"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleHandler handles role and permission endpoints
+type RoleHandler struct {
+	roleUseCase *usecase.RoleUseCase
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(roleUseCase *usecase.RoleUseCase) *RoleHandler {
+	return &RoleHandler{
+		roleUseCase: roleUseCase,
+	}
+}
+
+// GetRoles handles listing roles
+// @Summary List roles
+// @Description List all roles and their permissions
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} domain.Role
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /roles [get]
+func (h *RoleHandler) GetRoles(c *gin.Context) {
+	roles, err := h.roleUseCase.ListRoles(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+// GetPermissions handles listing the available permissions
+// @Summary List permissions
+// @Description List every permission that can be granted to a role
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /roles/permissions [get]
+func (h *RoleHandler) GetPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, domain.AllPermissions)
+}
+
+// CreateRole handles creating a role
+// @Summary Create role
+// @Description Create a custom role with a set of permissions
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.CreateRoleRequest true "Role creation request"
+// @Success 201 {object} domain.Role
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /roles [post]
+func (h *RoleHandler) CreateRole(c *gin.Context) {
+	var req domain.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.roleUseCase.CreateRole(c.Request.Context(), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, role)
+}
+
+// UpdateRole handles updating a role
+// @Summary Update role
+// @Description Update the description and permissions of a role. Users pick up the change on their next login or token refresh.
+// @Tags roles
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Param request body domain.UpdateRoleRequest true "Role update request"
+// @Success 200 {object} domain.Role
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /roles/{name} [put]
+func (h *RoleHandler) UpdateRole(c *gin.Context) {
+	var req domain.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	role, err := h.roleUseCase.UpdateRole(c.Request.Context(), c.Param("name"), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, role)
+}
+
+// DeleteRole handles deleting a role
+// @Summary Delete role
+// @Description Delete a custom role that is not assigned to any user
+// @Tags roles
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Role name"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /roles/{name} [delete]
+func (h *RoleHandler) DeleteRole(c *gin.Context) {
+	if err := h.roleUseCase.DeleteRole(c.Request.Context(), c.Param("name")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "role deleted successfully"})
+}
+
+// handleError maps role errors to HTTP responses
+func (h *RoleHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case err.Error() == "role not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "role already exists":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "invalid role name",
+		err.Error() == "admin role permissions cannot be modified",
+		err.Error() == "system roles cannot be deleted",
+		err.Error() == "role is assigned to users",
+		strings.HasPrefix(err.Error(), "unknown permission"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"agricultural-equipment-store/internal/config"
 	"agricultural-equipment-store/internal/delivery/http/middleware"
+	"agricultural-equipment-store/internal/domain"
 	"agricultural-equipment-store/internal/infrastructure/logger"
 	"agricultural-equipment-store/internal/usecase"
 	"context"
@@ -20,6 +21,10 @@ type Server struct {
 	config           *config.Config
 	logger           logger.Logger
 	authUseCase      *usecase.AuthUseCase
+	userUseCase      *usecase.UserUseCase
+	roleUseCase      *usecase.RoleUseCase
+	apiKeyUseCase    *usecase.APIKeyUseCase
+	oidcUseCase      *usecase.OIDCUseCase // nil when no identity provider is configured
 	productUseCase   *usecase.ProductUseCase
 	inventoryUseCase *usecase.InventoryUseCase
 	saleUseCase      *usecase.SaleUseCase
@@ -32,6 +37,10 @@ func NewServer(
 	config *config.Config,
 	logger logger.Logger,
 	authUseCase *usecase.AuthUseCase,
+	userUseCase *usecase.UserUseCase,
+	roleUseCase *usecase.RoleUseCase,
+	apiKeyUseCase *usecase.APIKeyUseCase,
+	oidcUseCase *usecase.OIDCUseCase,
 	productUseCase *usecase.ProductUseCase,
 	inventoryUseCase *usecase.InventoryUseCase,
 	saleUseCase *usecase.SaleUseCase,
@@ -41,6 +50,10 @@ func NewServer(
 		config:           config,
 		logger:           logger,
 		authUseCase:      authUseCase,
+		userUseCase:      userUseCase,
+		roleUseCase:      roleUseCase,
+		apiKeyUseCase:    apiKeyUseCase,
+		oidcUseCase:      oidcUseCase,
 		productUseCase:   productUseCase,
 		inventoryUseCase: inventoryUseCase,
 		saleUseCase:      saleUseCase,
@@ -63,24 +76,31 @@ func (s *Server) Start() error {
 	// CORS configuration
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = []string{s.config.Frontend.URL}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
-	corsConfig.ExposeHeaders = []string{"Content-Length"}
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "If-Match"}
+	corsConfig.ExposeHeaders = []string{"Content-Length", "ETag"}
 	corsConfig.AllowCredentials = true
 	router.Use(cors.New(corsConfig))
 
 	// Initialize handlers
 	authHandler := NewAuthHandler(s.authUseCase)
+	userHandler := NewUserHandler(s.userUseCase)
+	roleHandler := NewRoleHandler(s.roleUseCase)
+	apiKeyHandler := NewAPIKeyHandler(s.apiKeyUseCase)
+	var oidcHandler *OIDCHandler
+	if s.oidcUseCase != nil {
+		oidcHandler = NewOIDCHandler(s.oidcUseCase)
+	}
 	productHandler := NewProductHandler(s.productUseCase)
 	inventoryHandler := NewInventoryHandler(s.inventoryUseCase)
 	saleHandler := NewSaleHandler(s.saleUseCase)
 	categoryHandler := NewCategoryHandler(s.categoryUseCase)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(s.authUseCase)
+	authMiddleware := middleware.NewAuthMiddleware(s.authUseCase, s.apiKeyUseCase)
 
 	// Setup routes
-	s.setupRoutes(router, authHandler, productHandler, inventoryHandler, saleHandler, categoryHandler, authMiddleware)
+	s.setupRoutes(router, authHandler, userHandler, roleHandler, apiKeyHandler, oidcHandler, productHandler, inventoryHandler, saleHandler, categoryHandler, authMiddleware)
 
 	// Create HTTP server
 	s.server = &http.Server{
@@ -108,6 +128,10 @@ func (s *Server) Shutdown() error {
 func (s *Server) setupRoutes(
 	router *gin.Engine,
 	authHandler *AuthHandler,
+	userHandler *UserHandler,
+	roleHandler *RoleHandler,
+	apiKeyHandler *APIKeyHandler,
+	oidcHandler *OIDCHandler,
 	productHandler *ProductHandler,
 	inventoryHandler *InventoryHandler,
 	saleHandler *SaleHandler,
@@ -122,6 +146,9 @@ func (s *Server) setupRoutes(
 		})
 	})
 
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", authHandler.GetJWKS)
+
 	// Swagger documentation
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -136,38 +163,117 @@ func (s *Server) setupRoutes(
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.RefreshToken)
+			auth.POST("/logout", authMiddleware.RequireAuth(), authHandler.Logout)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
+			auth.POST("/resend-verification", authHandler.ResendVerification)
+			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.PUT("/password", authMiddleware.RequireAuth(), authHandler.ChangePassword)
 			auth.GET("/profile", authMiddleware.RequireAuth(), authHandler.GetProfile)
+			auth.PUT("/profile", authMiddleware.RequireAuth(), authHandler.UpdateProfile)
+			auth.DELETE("/profile", authMiddleware.RequireAuth(), authHandler.DeleteProfile)
+			auth.POST("/profile/confirm-email", authHandler.ConfirmEmailChange)
+			auth.POST("/mfa/verify", authHandler.VerifyMFA)
+			auth.POST("/mfa/setup", authMiddleware.RequireAuth(), authHandler.SetupMFA)
+			auth.POST("/mfa/enable", authMiddleware.RequireAuth(), authHandler.EnableMFA)
+			auth.POST("/mfa/disable", authMiddleware.RequireAuth(), authHandler.DisableMFA)
+			auth.POST("/mfa/recovery-codes", authMiddleware.RequireAuth(), authHandler.RegenerateRecoveryCodes)
+
+			// Login through the company identity provider, when one is configured
+			if oidcHandler != nil {
+				auth.GET("/oidc/authorize", oidcHandler.Authorize)
+				auth.POST("/oidc/callback", oidcHandler.Callback)
+			}
+		}
+
+		// User management routes
+		users := api.Group("/users")
+		{
+			users.POST("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionUsersManage), userHandler.CreateUser)
+			users.GET("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionUsersManage), userHandler.GetUsers)
+			users.GET("/failed-logins", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionUsersManage), userHandler.GetFailedLogins)
+			users.GET("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionUsersManage), userHandler.GetUser)
+			users.PUT("/:id/status", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionUsersManage), userHandler.UpdateUserStatus)
+			users.PUT("/:id/role", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionUsersManage), userHandler.UpdateUserRole)
+			users.POST("/:id/unlock", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionUsersManage), userHandler.UnlockUser)
+			users.DELETE("/:id/mfa", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionUsersManage), userHandler.ResetUserMFA)
+			users.DELETE("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionUsersManage), userHandler.DeleteUser)
+		}
+
+		// Role routes
+		roles := api.Group("/roles")
+		{
+			roles.GET("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionRolesManage), roleHandler.GetRoles)
+			roles.GET("/permissions", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionRolesManage), roleHandler.GetPermissions)
+			roles.POST("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionRolesManage), roleHandler.CreateRole)
+			roles.PUT("/:name", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionRolesManage), roleHandler.UpdateRole)
+			roles.DELETE("/:name", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionRolesManage), roleHandler.DeleteRole)
+		}
+
+		// API key routes
+		apiKeys := api.Group("/api-keys")
+		{
+			apiKeys.GET("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionAPIKeysManage), apiKeyHandler.GetAPIKeys)
+			apiKeys.POST("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionAPIKeysManage), apiKeyHandler.CreateAPIKey)
+			apiKeys.GET("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionAPIKeysManage), apiKeyHandler.GetAPIKey)
+			apiKeys.DELETE("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionAPIKeysManage), apiKeyHandler.RevokeAPIKey)
 		}
 
 		// Product routes
 		products := api.Group("/products")
 		{
 			// Public routes
-			products.GET("", productHandler.GetProducts)    // Get all products (public)
-			products.GET("/:id", productHandler.GetProduct) // Get single product (public)
+			products.GET("", productHandler.GetProducts)                             // Get all products (public)
+			products.GET("/lookup", productHandler.LookupProduct)                    // Look up product by SKU or barcode (public)
+			products.GET("/:id", productHandler.GetProduct)                          // Get single product (public)
+			products.GET("/:id/price-history", productHandler.GetPriceHistory)       // Get price history for charting (public)
+			products.GET("/:id/compatible-parts", productHandler.GetCompatibleParts) // Get parts that fit a machine (public)
+			products.GET("/:id/fits", productHandler.GetPartFits)                    // Get machines a part fits (public)
+
+			// Authenticated routes
+			products.GET("/export", authMiddleware.RequireAuth(), productHandler.ExportProducts)
 
 			// Admin routes
-			products.POST("", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), productHandler.CreateProduct)
-			products.PUT("/:id", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), productHandler.UpdateProduct)
-			products.DELETE("/:id", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), productHandler.DeleteProduct)
+			products.POST("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.CreateProduct)
+			products.POST("/import", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.ImportProducts)
+			products.POST("/bulk", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.BulkUpdateProducts)
+			products.POST("/compatibility", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.BulkUpdateCompatibility)
+			products.PUT("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.UpdateProduct)
+			products.PATCH("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.PatchProduct)
+			products.DELETE("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.DeleteProduct)
+			products.GET("/trash", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.GetDeletedProducts)
+			products.POST("/:id/restore", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.RestoreProduct)
+			products.GET("/:id/history", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.GetProductHistory)
+		}
+
+		// Spec attribute routes
+		specAttributes := api.Group("/spec-attributes")
+		{
+			specAttributes.GET("", productHandler.GetSpecAttributes) // Get all spec attributes (public)
+
+			// Admin routes
+			specAttributes.POST("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.CreateSpecAttribute)
+			specAttributes.PUT("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.UpdateSpecAttribute)
+			specAttributes.DELETE("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionProductsWrite), productHandler.DeleteSpecAttribute)
 		}
 
 		// Inventory routes
 		inventories := api.Group("/inventories")
 		{
-			inventories.PUT("/:id/stock", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), inventoryHandler.UpdateStock)
-			inventories.GET("/low-stock", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), inventoryHandler.GetLowStockProducts)
-			inventories.GET("/summary", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), inventoryHandler.GetStockSummary)
+			inventories.PUT("/:id/stock", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionInventoryAdjust), inventoryHandler.UpdateStock)
+			inventories.GET("/low-stock", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionInventoryView), inventoryHandler.GetLowStockProducts)
+			inventories.GET("/summary", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionInventoryView), inventoryHandler.GetStockSummary)
 		}
 
 		// Sales routes
 		sales := api.Group("/sales")
 		{
-			sales.POST("", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), saleHandler.CreateSale)
-			sales.GET("", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), saleHandler.GetSales)
-			sales.GET("/summary", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), saleHandler.GetSalesSummary)
-			sales.GET("/by-product", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), saleHandler.GetSalesByProduct)
-			sales.GET("/export", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), saleHandler.ExportSales)
+			sales.POST("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionSalesCreate), authMiddleware.RequireVerifiedEmailForPurchase(), saleHandler.CreateSale)
+			sales.GET("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionSalesView), saleHandler.GetSales)
+			sales.GET("/summary", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionReportsView), saleHandler.GetSalesSummary)
+			sales.GET("/by-product", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionReportsView), saleHandler.GetSalesByProduct)
+			sales.GET("/export", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionSalesView), saleHandler.ExportSales)
 		}
 
 		// Category routes
@@ -178,33 +284,89 @@ func (s *Server) setupRoutes(
 			categories.GET("/:id", categoryHandler.GetCategory) // Get single category (public)
 
 			// Admin routes
-			categories.POST("", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), categoryHandler.CreateCategory)
-			categories.DELETE("/:id", authMiddleware.RequireAuth(), authMiddleware.RequireAdmin(), categoryHandler.DeleteCategory)
+			categories.POST("", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionCategoriesWrite), categoryHandler.CreateCategory)
+			categories.PUT("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionCategoriesWrite), categoryHandler.UpdateCategory)
+			categories.DELETE("/:id", authMiddleware.RequireAuth(), authMiddleware.RequirePermission(domain.PermissionCategoriesWrite), categoryHandler.DeleteCategory)
 		}
 	}
 
 	// Print routes for debugging
 	s.logger.Info("API Routes:")
+	s.logger.Info("GET    /.well-known/jwks.json")
 	s.logger.Info("POST   /api/auth/register")
 	s.logger.Info("POST   /api/auth/login")
+	s.logger.Info("POST   /api/auth/refresh")
+	s.logger.Info("POST   /api/auth/logout")
+	s.logger.Info("POST   /api/auth/verify-email")
+	s.logger.Info("POST   /api/auth/resend-verification")
+	s.logger.Info("POST   /api/auth/forgot-password")
+	s.logger.Info("POST   /api/auth/reset-password")
+	s.logger.Info("PUT    /api/auth/password")
 	s.logger.Info("GET    /api/auth/profile")
+	s.logger.Info("PUT    /api/auth/profile")
+	s.logger.Info("DELETE /api/auth/profile")
+	s.logger.Info("POST   /api/auth/profile/confirm-email")
+	s.logger.Info("POST   /api/auth/mfa/verify")
+	s.logger.Info("POST   /api/auth/mfa/setup")
+	s.logger.Info("POST   /api/auth/mfa/enable")
+	s.logger.Info("POST   /api/auth/mfa/disable")
+	s.logger.Info("POST   /api/auth/mfa/recovery-codes")
+	s.logger.Info("POST   /api/users (users:manage)")
+	if s.oidcUseCase != nil {
+		s.logger.Info("GET    /api/auth/oidc/authorize")
+		s.logger.Info("POST   /api/auth/oidc/callback")
+	}
+	s.logger.Info("GET    /api/users (users:manage)")
+	s.logger.Info("GET    /api/users/failed-logins (users:manage)")
+	s.logger.Info("GET    /api/users/:id (users:manage)")
+	s.logger.Info("PUT    /api/users/:id/status (users:manage)")
+	s.logger.Info("PUT    /api/users/:id/role (users:manage)")
+	s.logger.Info("POST   /api/users/:id/unlock (users:manage)")
+	s.logger.Info("DELETE /api/users/:id/mfa (users:manage)")
+	s.logger.Info("DELETE /api/users/:id (users:manage)")
+	s.logger.Info("GET    /api/roles (roles:manage)")
+	s.logger.Info("GET    /api/roles/permissions (roles:manage)")
+	s.logger.Info("POST   /api/roles (roles:manage)")
+	s.logger.Info("PUT    /api/roles/:name (roles:manage)")
+	s.logger.Info("DELETE /api/roles/:name (roles:manage)")
+	s.logger.Info("GET    /api/api-keys (api_keys:manage)")
+	s.logger.Info("POST   /api/api-keys (api_keys:manage)")
+	s.logger.Info("GET    /api/api-keys/:id (api_keys:manage)")
+	s.logger.Info("DELETE /api/api-keys/:id (api_keys:manage)")
 	s.logger.Info("GET    /api/products")
+	s.logger.Info("GET    /api/products/lookup")
+	s.logger.Info("GET    /api/products/export")
 	s.logger.Info("GET    /api/products/:id")
-	s.logger.Info("POST   /api/products (admin)")
-	s.logger.Info("PUT    /api/products/:id (admin)")
-	s.logger.Info("DELETE /api/products/:id (admin)")
-	s.logger.Info("PUT    /api/inventories/:id/stock (admin)")
-	s.logger.Info("GET    /api/inventories/low-stock (admin)")
-	s.logger.Info("GET    /api/inventories/summary (admin)")
-	s.logger.Info("POST   /api/sales (admin)")
-	s.logger.Info("GET    /api/sales (admin)")
-	s.logger.Info("GET    /api/sales/summary (admin)")
-	s.logger.Info("GET    /api/sales/by-product (admin)")
-	s.logger.Info("GET    /api/sales/export (admin)")
+	s.logger.Info("GET    /api/products/:id/price-history")
+	s.logger.Info("GET    /api/products/:id/compatible-parts")
+	s.logger.Info("GET    /api/products/:id/fits")
+	s.logger.Info("POST   /api/products (products:write)")
+	s.logger.Info("POST   /api/products/import (products:write)")
+	s.logger.Info("POST   /api/products/bulk (products:write)")
+	s.logger.Info("POST   /api/products/compatibility (products:write)")
+	s.logger.Info("PUT    /api/products/:id (products:write)")
+	s.logger.Info("PATCH  /api/products/:id (products:write)")
+	s.logger.Info("DELETE /api/products/:id (products:write)")
+	s.logger.Info("GET    /api/products/trash (products:write)")
+	s.logger.Info("POST   /api/products/:id/restore (products:write)")
+	s.logger.Info("GET    /api/products/:id/history (products:write)")
+	s.logger.Info("GET    /api/spec-attributes")
+	s.logger.Info("POST   /api/spec-attributes (products:write)")
+	s.logger.Info("PUT    /api/spec-attributes/:id (products:write)")
+	s.logger.Info("DELETE /api/spec-attributes/:id (products:write)")
+	s.logger.Info("PUT    /api/inventories/:id/stock (inventory:adjust)")
+	s.logger.Info("GET    /api/inventories/low-stock (inventory:view)")
+	s.logger.Info("GET    /api/inventories/summary (inventory:view)")
+	s.logger.Info("POST   /api/sales (sales:create)")
+	s.logger.Info("GET    /api/sales (sales:view)")
+	s.logger.Info("GET    /api/sales/summary (reports:view)")
+	s.logger.Info("GET    /api/sales/by-product (reports:view)")
+	s.logger.Info("GET    /api/sales/export (sales:view)")
 	s.logger.Info("GET    /api/categories")
 	s.logger.Info("GET    /api/categories/:id")
-	s.logger.Info("POST   /api/categories (admin)")
-	s.logger.Info("DELETE /api/categories/:id (admin)")
+	s.logger.Info("POST   /api/categories (categories:write)")
+	s.logger.Info("PUT    /api/categories/:id (categories:write)")
+	s.logger.Info("DELETE /api/categories/:id (categories:write)")
 	s.logger.Info("GET    /swagger/index.html")
 }
 
@@ -223,24 +385,31 @@ func (s *Server) GetRouter() *gin.Engine {
 	// CORS configuration - allow all origins for serverless
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowAllOrigins = true
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
-	corsConfig.ExposeHeaders = []string{"Content-Length"}
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "If-Match"}
+	corsConfig.ExposeHeaders = []string{"Content-Length", "ETag"}
 	corsConfig.AllowCredentials = true
 	router.Use(cors.New(corsConfig))
 
 	// Initialize handlers
 	authHandler := NewAuthHandler(s.authUseCase)
+	userHandler := NewUserHandler(s.userUseCase)
+	roleHandler := NewRoleHandler(s.roleUseCase)
+	apiKeyHandler := NewAPIKeyHandler(s.apiKeyUseCase)
+	var oidcHandler *OIDCHandler
+	if s.oidcUseCase != nil {
+		oidcHandler = NewOIDCHandler(s.oidcUseCase)
+	}
 	productHandler := NewProductHandler(s.productUseCase)
 	inventoryHandler := NewInventoryHandler(s.inventoryUseCase)
 	saleHandler := NewSaleHandler(s.saleUseCase)
 	categoryHandler := NewCategoryHandler(s.categoryUseCase)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(s.authUseCase)
+	authMiddleware := middleware.NewAuthMiddleware(s.authUseCase, s.apiKeyUseCase)
 
 	// Setup routes
-	s.setupRoutes(router, authHandler, productHandler, inventoryHandler, saleHandler, categoryHandler, authMiddleware)
+	s.setupRoutes(router, authHandler, userHandler, roleHandler, apiKeyHandler, oidcHandler, productHandler, inventoryHandler, saleHandler, categoryHandler, authMiddleware)
 
 	return router
 }
@@ -0,0 +1,429 @@
+package http
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserHandler handles user management endpoints
+type UserHandler struct {
+	userUseCase *usecase.UserUseCase
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(userUseCase *usecase.UserUseCase) *UserHandler {
+	return &UserHandler{
+		userUseCase: userUseCase,
+	}
+}
+
+// CreateUser handles creating a staff account
+// @Summary Create user
+// @Description Create a staff account with the given role (admin only)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body domain.AdminCreateUserRequest true "User creation request"
+// @Success 201 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /users [post]
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req domain.AdminCreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userUseCase.CreateUser(c.Request.Context(), currentPermissions(c), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// GetUsers handles listing users
+// @Summary List users
+// @Description List and search user accounts (admin only)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param search query string false "Search in name and email"
+// @Param role query string false "Role filter"
+// @Param is_active query bool false "Active status filter"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 10)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /users [get]
+func (h *UserHandler) GetUsers(c *gin.Context) {
+	filter := domain.UserFilter{
+		Search: c.Query("search"),
+		Role:   c.Query("role"),
+	}
+
+	if isActiveStr := c.Query("is_active"); isActiveStr != "" {
+		if isActive, err := strconv.ParseBool(isActiveStr); err == nil {
+			filter.IsActive = &isActive
+		}
+	}
+
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil {
+			filter.Page = page
+		}
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			filter.Limit = limit
+		}
+	}
+
+	users, count, err := h.userUseCase.ListUsers(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// ListUsers applies the default pagination, mirror it for the response
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	totalPages := (count + int64(filter.Limit) - 1) / int64(filter.Limit)
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":       users,
+		"total":       count,
+		"page":        filter.Page,
+		"limit":       filter.Limit,
+		"total_pages": totalPages,
+	})
+}
+
+// GetUser handles getting a user by ID
+// @Summary Get user by ID
+// @Description Get a single user account (admin only)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id} [get]
+func (h *UserHandler) GetUser(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	user, err := h.userUseCase.GetUser(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUserStatus handles activating or deactivating a user
+// @Summary Activate or deactivate user
+// @Description Deactivate a user to block login and end their sessions, or reactivate them (admin only)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body domain.UpdateUserStatusRequest true "Status update request"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /users/{id}/status [put]
+func (h *UserHandler) UpdateUserStatus(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req domain.UpdateUserStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userUseCase.SetUserActive(c.Request.Context(), actorID, currentPermissions(c), id, *req.IsActive)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUserRole handles changing a user's role
+// @Summary Change user role
+// @Description Change the role of a user (admin only)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body domain.UpdateUserRoleRequest true "Role update request"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/role [put]
+func (h *UserHandler) UpdateUserRole(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	var req domain.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.userUseCase.ChangeUserRole(c.Request.Context(), actorID, currentPermissions(c), id, req.Role)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// UnlockUser handles lifting a login lockout
+// @Summary Unlock user
+// @Description Clear the failed login counter of a user and lift any lockout (admin only)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/unlock [post]
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	user, err := h.userUseCase.UnlockUser(c.Request.Context(), currentPermissions(c), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// ResetUserMFA handles turning off two-factor authentication for a user
+// @Summary Reset user MFA
+// @Description Turn off two-factor authentication for a user who lost their authenticator and recovery codes, and end their sessions (admin only)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} domain.User
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/mfa [delete]
+func (h *UserHandler) ResetUserMFA(c *gin.Context) {
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	user, err := h.userUseCase.ResetMFA(c.Request.Context(), currentPermissions(c), id)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// GetFailedLogins handles listing failed login attempts
+// @Summary List failed logins
+// @Description List failed login attempts, newest first (admin only)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param email query string false "Email filter"
+// @Param ip_address query string false "IP address filter"
+// @Param user_id query string false "User ID filter"
+// @Param page query int false "Page number (default 1)"
+// @Param limit query int false "Items per page (default 10)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /users/failed-logins [get]
+func (h *UserHandler) GetFailedLogins(c *gin.Context) {
+	filter := domain.FailedLoginFilter{
+		Email:     c.Query("email"),
+		IPAddress: c.Query("ip_address"),
+	}
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := primitive.ObjectIDFromHex(userIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	if pageStr := c.Query("page"); pageStr != "" {
+		if page, err := strconv.Atoi(pageStr); err == nil {
+			filter.Page = page
+		}
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			filter.Limit = limit
+		}
+	}
+
+	attempts, count, err := h.userUseCase.ListFailedLogins(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// ListFailedLogins applies the default pagination, mirror it for the response
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	totalPages := (count + int64(filter.Limit) - 1) / int64(filter.Limit)
+
+	c.JSON(http.StatusOK, gin.H{
+		"failed_logins": attempts,
+		"total":         count,
+		"page":          filter.Page,
+		"limit":         filter.Limit,
+		"total_pages":   totalPages,
+	})
+}
+
+// DeleteUser handles deleting a user
+// @Summary Delete user
+// @Description Delete a user account and end all of its sessions (admin only)
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	actorID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	if err := h.userUseCase.DeleteUser(c.Request.Context(), actorID, currentPermissions(c), id); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
+}
+
+// handleError maps user management errors to HTTP responses
+func (h *UserHandler) handleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "user already exists":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "cannot grant a role with permissions you do not have", "cannot change the role of a user with permissions you do not have",
+		"cannot manage a user with permissions you do not have":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "cannot deactivate the last administrator account", "cannot delete the last administrator account":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "invalid role", "cannot deactivate your own account", "cannot change your own role", "cannot delete your own account":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// currentUserID returns the ID of the authenticated user
+func currentUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return primitive.NilObjectID, false
+	}
+
+	idStr, ok := userID.(string)
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+
+	id, err := primitive.ObjectIDFromHex(idStr)
+	if err != nil {
+		return primitive.NilObjectID, false
+	}
+	return id, true
+}
+
+// currentPermissions returns the permissions of the authenticated user
+func currentPermissions(c *gin.Context) []string {
+	permissions, _ := c.Get("user_permissions")
+	granted, _ := permissions.([]string)
+	return granted
+}
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Actor identifies the authenticated user making a change, recorded in
+// audit trails such as product history
+type Actor struct {
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Email  string             `json:"email" bson:"email"`
+}
+
+// actorContextKey is the context key of the actor
+type actorContextKey struct{}
+
+// ContextWithActor returns a copy of the context carrying the actor
+func ContextWithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor of a request context, if any
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorContextKey{}).(Actor)
+	return actor, ok
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKey represents a long-lived key used by devices and integrations instead of a password.
+// A key acts on behalf of a user and is limited to its scopes.
+type APIKey struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name       string             `json:"name" bson:"name"`
+	Prefix     string             `json:"prefix" bson:"prefix"`   // First characters of the key, to tell keys apart
+	KeyHash    string             `json:"-" bson:"key_hash"`      // SHA-256 of the key, the key itself is only shown once
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"` // User the key acts as
+	Scopes     []string           `json:"scopes" bson:"scopes"`   // Permissions the key may use
+	CreatedBy  primitive.ObjectID `json:"created_by" bson:"created_by"`
+	ExpiresAt  *time.Time         `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `json:"last_used_at,omitempty" bson:"last_used_at,omitempty"`
+	LastUsedIP string             `json:"last_used_ip,omitempty" bson:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time         `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// CreateAPIKeyRequest represents the request payload for creating an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	UserID    string     `json:"user_id" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResponse represents the response payload for a new API key
+type CreateAPIKeyResponse struct {
+	Key    string `json:"key"` // Only returned once
+	APIKey APIKey `json:"api_key"`
+}
+
+// APIKeyFilter represents filter options for API keys
+type APIKeyFilter struct {
+	UserID         *primitive.ObjectID `json:"user_id"`
+	IncludeRevoked bool                `json:"include_revoked"`
+	Page           int                 `json:"page"`
+	Limit          int                 `json:"limit"`
+}
+
+// APIKeyIdentity represents the user and permissions an API key authenticates as
+type APIKeyIdentity struct {
+	Key         *APIKey
+	User        *User
+	Permissions []string
+}
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxCompatibilityChanges is the largest number of mappings a single bulk
+// compatibility change sets and removes
+const MaxCompatibilityChanges = 5000
+
+// PartCompatibility records that a part product, such as a filter or a
+// blade, fits a machine product, such as a tractor model. A part is mapped
+// to a machine at most once.
+type PartCompatibility struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	PartID    primitive.ObjectID `json:"part_id" bson:"part_id"`
+	MachineID primitive.ObjectID `json:"machine_id" bson:"machine_id"`
+	Fitment   `bson:",inline"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Fitment limits which machines of a model a part fits by model year and
+// serial number. Bounds are inclusive and empty bounds are open.
+type Fitment struct {
+	YearFrom   int    `json:"year_from,omitempty" bson:"year_from"`
+	YearTo     int    `json:"year_to,omitempty" bson:"year_to"`
+	SerialFrom string `json:"serial_from,omitempty" bson:"serial_from"` // Serials compare with their digits as numbers, so SN9 comes before SN10
+	SerialTo   string `json:"serial_to,omitempty" bson:"serial_to"`
+	Notes      string `json:"notes,omitempty" bson:"notes"` // E.g. "needs adapter kit 3A-112"
+}
+
+// CompatibleProduct represents a part that fits a machine, or a machine a
+// part fits, with the fitment of the mapping
+type CompatibleProduct struct {
+	Product *Product `json:"product"`
+	Fitment
+}
+
+// CompatibilityFilter narrows the parts that fit a machine to a specific
+// machine and a category
+type CompatibilityFilter struct {
+	Year     int    // Model year of the machine, 0 for any
+	Serial   string // Serial number of the machine, empty for any
+	Category string
+}
+
+// CompatibilityMapping represents a part and machine pair in a bulk
+// compatibility change
+type CompatibilityMapping struct {
+	PartID    primitive.ObjectID `json:"part_id" binding:"required"`
+	MachineID primitive.ObjectID `json:"machine_id" binding:"required"`
+	Fitment
+}
+
+// CompatibilityBulkRequest represents the request payload for maintaining
+// many part compatibility mappings at once
+type CompatibilityBulkRequest struct {
+	Set    []CompatibilityMapping `json:"set" binding:"omitempty,dive"`    // Added, or replacing the fitment of an existing mapping
+	Remove []CompatibilityMapping `json:"remove" binding:"omitempty,dive"` // Only the part and machine are used
+}
+
+// CompatibilityBulkResult represents the outcome of a bulk compatibility change
+type CompatibilityBulkResult struct {
+	Set     int `json:"set"`
+	Removed int `json:"removed"` // Mappings that existed and were removed
+}
@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reasons a login attempt failed
+const (
+	LoginFailureUnknownEmail    = "unknown_email"
+	LoginFailureInvalidPassword = "invalid_password"
+	LoginFailureInvalidMFACode  = "invalid_mfa_code"
+	LoginFailureAccountLocked   = "account_locked"
+	LoginFailureIPThrottled     = "ip_throttled"
+)
+
+// FailedLogin represents a failed login attempt, kept as an audit trail and to throttle clients by IP address
+type FailedLogin struct {
+	ID        primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Email     string              `json:"email" bson:"email"`
+	UserID    *primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"` // Empty when the email is not registered
+	IPAddress string              `json:"ip_address" bson:"ip_address"`
+	UserAgent string              `json:"user_agent" bson:"user_agent"`
+	Reason    string              `json:"reason" bson:"reason"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// FailedLoginFilter represents filter options for failed logins
+type FailedLoginFilter struct {
+	Email     string              `json:"email"`
+	IPAddress string              `json:"ip_address"`
+	UserID    *primitive.ObjectID `json:"user_id"`
+	Page      int                 `json:"page"`
+	Limit     int                 `json:"limit"`
+}
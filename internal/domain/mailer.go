@@ -0,0 +1,15 @@
+package domain
+
+import "context"
+
+// EmailMessage represents an email sent to a user
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string // Plain text body
+}
+
+// Mailer defines the interface for delivering emails
+type Mailer interface {
+	Send(ctx context.Context, msg EmailMessage) error
+}
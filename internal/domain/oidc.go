@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExternalIdentity represents a user authenticated by an OpenID Connect provider
+type ExternalIdentity struct {
+	Issuer        string   `json:"issuer"`
+	Subject       string   `json:"subject"` // Stable user ID at the provider
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+	Name          string   `json:"name"`
+	Groups        []string `json:"groups"`
+	Nonce         string   `json:"-"`
+}
+
+// IdentityProvider runs the authorization code flow against an OpenID Connect provider
+type IdentityProvider interface {
+	// AuthCodeURL returns the URL that starts a login at the provider
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange redeems an authorization code and returns the verified identity of the user
+	Exchange(ctx context.Context, code, codeVerifier string) (*ExternalIdentity, error)
+}
+
+// OIDCState represents a login started at the identity provider that has not
+// come back yet
+type OIDCState struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	StateHash    string             `json:"-" bson:"state_hash"`    // SHA-256 of the state parameter
+	CodeVerifier string             `json:"-" bson:"code_verifier"` // PKCE verifier, never leaves the server
+	Nonce        string             `json:"-" bson:"nonce"`
+	ExpiresAt    time.Time          `json:"expires_at" bson:"expires_at"`
+	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// OIDCCallbackRequest represents the request payload for completing a login
+// with the parameters the identity provider redirected back with
+type OIDCCallbackRequest struct {
+	Code      string `json:"code" binding:"required"`
+	State     string `json:"state" binding:"required"`
+	Browser   string `json:"-"` // State from the cookie of the browser that started the login
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// OIDCAuthorizationResponse represents the response payload for starting a login at the identity provider
+type OIDCAuthorizationResponse struct {
+	AuthorizationURL string    `json:"authorization_url"`
+	ExpiresAt        time.Time `json:"expires_at"` // The login must be completed before this time
+	State            string    `json:"-"`          // Set as a cookie so only this browser can complete the login
+}
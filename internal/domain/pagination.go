@@ -0,0 +1,10 @@
+package domain
+
+// MaxPageLimit is the largest number of items a list endpoint returns per page
+const MaxPageLimit = 100
+
+// Sort orders
+const (
+	SortAscending  = "asc"
+	SortDescending = "desc"
+)
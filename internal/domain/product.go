@@ -9,17 +9,66 @@ import (
 // Product represents a product in the system
 type Product struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	SKU         string             `json:"sku" bson:"sku"`
+	Barcodes    []ProductBarcode   `json:"barcodes" bson:"barcodes"`
 	Name        string             `json:"name" bson:"name"`
 	Description string             `json:"description" bson:"description"`
 	Price       float64            `json:"price" bson:"price"`
 	Category    string             `json:"category" bson:"category"`
 	Brand       string             `json:"brand" bson:"brand"`
-	ImageURL    string             `json:"image_url" bson:"image_url"` // Legacy field for backward compatibility
-	Images      []ProductImage     `json:"images" bson:"images"`       // New field for multiple images
-	Stock       int                `json:"stock" bson:"stock"`
+	ImageURL    string             `json:"image_url" bson:"image_url"`   // Legacy field for backward compatibility
+	Images      []ProductImage     `json:"images" bson:"images"`         // New field for multiple images
+	Stock       int                `json:"stock" bson:"stock"`           // Total of the variant stock when the product has variants
+	Variants    []ProductVariant   `json:"variants" bson:"variants"`     // Sellable options such as tank sizes or blade widths
+	Specs       []ProductSpec      `json:"specs" bson:"specs"`           // Technical specifications such as horsepower or fuel type
+	SoldCount   int                `json:"sold_count" bson:"sold_count"` // Units sold, used to sort by popularity
 	IsActive    bool               `json:"is_active" bson:"is_active"`
+	Version     int64              `json:"version" bson:"version"` // Incremented on every change, sent as the ETag
 	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+
+	// Set while the product is in the trash
+	DeletedAt *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"` // User who deleted the product
+
+	// Set on search results only
+	Score      float64           `json:"score,omitempty" bson:"-"`      // Full-text relevance
+	Highlights map[string]string `json:"highlights,omitempty" bson:"-"` // Matching snippets by field, terms wrapped in <mark>
+}
+
+// ProductVariant represents a sellable option of a product with its own SKU,
+// price and stock. When a product has variants its Price is the lowest active
+// variant price and its Stock is the total variant stock.
+type ProductVariant struct {
+	ID         string            `json:"id" bson:"id"`                 // Unique ID for this variant
+	SKU        string            `json:"sku" bson:"sku"`               // Stock keeping unit
+	Barcodes   []ProductBarcode  `json:"barcodes" bson:"barcodes"`     // Barcodes printed on this variant
+	Name       string            `json:"name" bson:"name"`             // Display name, e.g. "20L tank"
+	Attributes map[string]string `json:"attributes" bson:"attributes"` // Distinguishing attributes, e.g. tank_size=20L
+	Price      float64           `json:"price" bson:"price"`
+	Stock      int               `json:"stock" bson:"stock"`
+	IsActive   bool              `json:"is_active" bson:"is_active"`
+}
+
+// Barcode types
+const (
+	BarcodeTypeEAN13    = "ean13"
+	BarcodeTypeUPC      = "upc"      // UPC-A, 12 digits
+	BarcodeTypeInternal = "internal" // Store-assigned code without a check digit
+)
+
+// ProductBarcode represents a barcode printed on a product. SKUs and
+// barcodes are unique across all products and variants.
+type ProductBarcode struct {
+	Code string `json:"code" bson:"code" binding:"required"`
+	Type string `json:"type" bson:"type"` // Detected from the code when empty
+}
+
+// ProductLookupResult represents the product, and variant, a scanned code belongs to
+type ProductLookupResult struct {
+	Product   *Product        `json:"product"`
+	Variant   *ProductVariant `json:"variant,omitempty"`
+	MatchedBy string          `json:"matched_by"` // sku or barcode
 }
 
 // ProductImage represents an image associated with a product
@@ -39,6 +88,7 @@ type ProductImage struct {
 type Category struct {
 	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
 	Name      string             `json:"name" bson:"name"`
+	Version   int64              `json:"version" bson:"version"` // Incremented on every change, sent as the ETag
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
 }
@@ -48,29 +98,79 @@ type CreateCategoryRequest struct {
 	Name string `json:"name" binding:"required"`
 }
 
+// UpdateCategoryRequest represents the request payload for renaming a category
+type UpdateCategoryRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
 // CreateProductRequest represents the request payload for creating a product
 type CreateProductRequest struct {
-	Name        string   `json:"name" binding:"required"`
-	Description string   `json:"description"`
-	Price       float64  `json:"price" binding:"required,gt=0"`
-	Category    string   `json:"category" binding:"required"`
-	Brand       string   `json:"brand"`
-	ImageURL    string   `json:"image_url"`  // Legacy field for backward compatibility
-	ImageURLs   []string `json:"image_urls"` // Multiple image URLs
-	Stock       int      `json:"stock" binding:"required,gte=0"`
+	SKU         string                  `json:"sku"`
+	Barcodes    []ProductBarcode        `json:"barcodes" binding:"omitempty,dive"`
+	Name        string                  `json:"name" binding:"required"`
+	Description string                  `json:"description"`
+	Price       float64                 `json:"price" binding:"required_without=Variants,omitempty,gt=0"`
+	Category    string                  `json:"category" binding:"required"`
+	Brand       string                  `json:"brand"`
+	ImageURL    string                  `json:"image_url"`  // Legacy field for backward compatibility
+	ImageURLs   []string                `json:"image_urls"` // Multiple image URLs
+	Stock       int                     `json:"stock" binding:"required_without=Variants,gte=0"`
+	Variants    []ProductVariantRequest `json:"variants" binding:"omitempty,dive"` // Price and stock are taken from the variants when given
+	Specs       []ProductSpec           `json:"specs" binding:"omitempty,dive"`
+}
+
+// ProductVariantRequest represents a product variant in create and update requests
+type ProductVariantRequest struct {
+	ID         string            `json:"id"` // Existing variant to update, empty for a new variant
+	SKU        string            `json:"sku" binding:"required"`
+	Barcodes   []ProductBarcode  `json:"barcodes" binding:"omitempty,dive"`
+	Name       string            `json:"name"`
+	Attributes map[string]string `json:"attributes"`
+	Price      float64           `json:"price" binding:"required,gt=0"`
+	Stock      int               `json:"stock" binding:"gte=0"`
+	IsActive   *bool             `json:"is_active"` // Defaults to true
 }
 
 // UpdateProductRequest represents the request payload for updating a product
 type UpdateProductRequest struct {
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Price       float64  `json:"price"`
-	Category    string   `json:"category"`
-	Brand       string   `json:"brand"`
-	ImageURL    string   `json:"image_url"`  // Legacy field for backward compatibility
-	ImageURLs   []string `json:"image_urls"` // Multiple image URLs
-	Stock       int      `json:"stock"`
-	IsActive    *bool    `json:"is_active"`
+	SKU         string                  `json:"sku"`
+	Barcodes    []ProductBarcode        `json:"barcodes" binding:"omitempty,dive"` // Replaces all barcodes when given, an empty list removes them
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Price       float64                 `json:"price"`
+	Category    string                  `json:"category"`
+	Brand       string                  `json:"brand"`
+	ImageURL    string                  `json:"image_url"`                       // Legacy field for backward compatibility
+	ImageURLs   []string                `json:"image_urls"`                      // Multiple image URLs
+	Stock       *int                    `json:"stock" binding:"omitempty,gte=0"` // The current stock is kept when left out
+	IsActive    *bool                   `json:"is_active"`
+	Variants    []ProductVariantRequest `json:"variants" binding:"omitempty,dive"` // Replaces all variants when given, an empty list removes them
+	Specs       []ProductSpec           `json:"specs" binding:"omitempty,dive"`    // Replaces all specs when given, an empty list removes them
+}
+
+// Product patch formats
+const (
+	PatchTypeMerge = "merge" // JSON Merge Patch (RFC 7396)
+	PatchTypeJSON  = "json"  // JSON Patch (RFC 6902)
+)
+
+// ProductPatchDocument represents the fields of a product that PATCH changes.
+// Patches apply to this document built from the product, and only the fields
+// whose value changes are saved. Variants and specs are replaced as a whole
+// list, image_urls replaces the URL images and keeps uploaded ones.
+type ProductPatchDocument struct {
+	SKU         string                  `json:"sku"`
+	Barcodes    []ProductBarcode        `json:"barcodes"`
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Price       float64                 `json:"price"` // Set from the variants when the product has variants
+	Category    string                  `json:"category"`
+	Brand       string                  `json:"brand"`
+	ImageURLs   []string                `json:"image_urls"`
+	Stock       int                     `json:"stock"` // Set from the variants when the product has variants
+	IsActive    bool                    `json:"is_active"`
+	Variants    []ProductVariantRequest `json:"variants"`
+	Specs       []ProductSpec           `json:"specs"`
 }
 
 // ProductFilter represents filter options for products
@@ -83,4 +183,59 @@ type ProductFilter struct {
 	Search   string  `json:"search"`
 	Page     int     `json:"page"`
 	Limit    int     `json:"limit"`
+
+	// PartialSearch matches search terms anywhere in the name, brand,
+	// description or SKU instead of using the full-text index. GetProducts
+	// falls back to it when the full-text search finds nothing.
+	PartialSearch bool `json:"-"`
+
+	// Sort is price, name, stock, created_at, popularity or, for searches,
+	// relevance. Order is asc or desc; name sorts ascending by default and
+	// the other fields descending.
+	Sort  string `json:"sort"`
+	Order string `json:"order"`
+
+	// Cursor switches from Page to keyset pagination. An empty cursor
+	// requests the first page, the next_cursor of a page the one after it.
+	Cursor *string `json:"cursor"`
+
+	// Variant attribute values, e.g. tank_size=20L. A product matches when
+	// one of its active variants has all of them, and the price range is then
+	// checked against that variant.
+	VariantAttributes map[string]string `json:"variant_attributes"`
+
+	// Spec attribute filters, e.g. hp_min=30 and fuel=diesel
+	Specs []SpecFilter `json:"specs"`
+
+	// Deleted lists the products in the trash instead of the catalog
+	Deleted bool `json:"deleted"`
+}
+
+// ProductFacets represents the facet counts of a product listing. The counts
+// are computed for the same filter as the listing.
+type ProductFacets struct {
+	Categories   []FacetCount      `json:"categories" bson:"categories"`
+	Brands       []FacetCount      `json:"brands" bson:"brands"`
+	PriceRanges  []PriceRangeFacet `json:"price_ranges" bson:"-"`
+	Availability AvailabilityFacet `json:"availability" bson:"-"`
+}
+
+// FacetCount represents the number of products with a value
+type FacetCount struct {
+	Value string `json:"value" bson:"_id"`
+	Count int64  `json:"count" bson:"count"`
+}
+
+// PriceRangeFacet represents the number of products with a price from Min up
+// to, but not including, Max. The highest range has no Max.
+type PriceRangeFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int64    `json:"count"`
+}
+
+// AvailabilityFacet represents the number of products in and out of stock
+type AvailabilityFacet struct {
+	InStock    int64 `json:"in_stock" bson:"in_stock"`
+	OutOfStock int64 `json:"out_of_stock" bson:"out_of_stock"`
 }
@@ -0,0 +1,46 @@
+package domain
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// MaxBulkProducts is the largest number of products a bulk update changes
+const MaxBulkProducts = 5000
+
+// BulkProductRequest represents the request payload for changing many
+// products at once. The products are given by ID or selected by a filter,
+// which includes inactive products unless is_active is set in it.
+type BulkProductRequest struct {
+	IDs      []string         `json:"ids"`
+	Filter   *ProductFilter   `json:"filter"`
+	Price    *PriceAdjustment `json:"price"`
+	IsActive *bool            `json:"is_active"`
+	Category string           `json:"category"`
+	Brand    string           `json:"brand"`
+	Preview  bool             `json:"preview"` // Return the changes without saving them
+}
+
+// PriceAdjustment changes product and variant prices by a percentage or an
+// amount, one of which is required, and rounds the result
+type PriceAdjustment struct {
+	Percent *float64 `json:"percent"`  // 5 raises prices by 5%, -10 lowers them by 10%
+	Amount  *float64 `json:"amount"`   // Added to the prices, negative to lower them
+	RoundTo float64  `json:"round_to"` // Prices are rounded to a multiple of it, 0.01 by default
+}
+
+// BulkProductResult represents the outcome of a bulk product update
+type BulkProductResult struct {
+	Preview  bool                `json:"preview"`
+	Matched  int                 `json:"matched"`
+	Changed  int                 `json:"changed"`
+	Failed   int                 `json:"failed"`   // Products that could not be saved, with the error on their change
+	Products []BulkProductChange `json:"products"` // The changed products
+}
+
+// BulkProductChange represents the changes made, or in a preview that would
+// be made, to one product
+type BulkProductChange struct {
+	ProductID primitive.ObjectID `json:"product_id"`
+	SKU       string             `json:"sku,omitempty"`
+	Name      string             `json:"name"`
+	Changes   []FieldChange      `json:"changes"`
+	Error     string             `json:"error,omitempty"` // Why the product was not saved
+}
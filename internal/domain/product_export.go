@@ -0,0 +1,8 @@
+package domain
+
+// Product export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+	ExportFormatJSON = "json"
+)
@@ -0,0 +1,35 @@
+package domain
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// MaxImportRows is the largest number of products a single import accepts
+const MaxImportRows = 5000
+
+// Product import row actions
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged" // The row matches the product as it is
+)
+
+// ProductImportResult represents the outcome of a product import. Rows with
+// errors are skipped, the other rows are saved unless it is a dry run.
+type ProductImportResult struct {
+	DryRun    bool               `json:"dry_run"`
+	Total     int                `json:"total"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Unchanged int                `json:"unchanged"`
+	Failed    int                `json:"failed"`
+	Rows      []ProductImportRow `json:"rows"`
+}
+
+// ProductImportRow represents the outcome of one row of an import file
+type ProductImportRow struct {
+	Row       int                 `json:"row"`              // Line in the file, the header is row 1
+	Action    string              `json:"action,omitempty"` // Empty when the row has errors
+	ProductID *primitive.ObjectID `json:"product_id,omitempty"`
+	SKU       string              `json:"sku,omitempty"`
+	Name      string              `json:"name,omitempty"`
+	Errors    []string            `json:"errors,omitempty"`
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Product revision actions
+const (
+	RevisionActionCreate  = "create"
+	RevisionActionUpdate  = "update"
+	RevisionActionStock   = "stock" // Stock set through the inventory
+	RevisionActionSale    = "sale"  // Stock taken by a sale
+	RevisionActionDelete  = "delete"
+	RevisionActionRestore = "restore"
+)
+
+// ProductRevision records a change to a product, who made it and when
+type ProductRevision struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
+	Action    string             `json:"action" bson:"action"`
+	Changes   []FieldChange      `json:"changes" bson:"changes"`
+	Actor     *Actor             `json:"actor,omitempty" bson:"actor,omitempty"` // Empty for changes made by the system
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// FieldChange represents the old and new value of a product field. Variant
+// fields are named variants.<field> and carry the variant ID; a variant
+// that was added or removed is a variants change with the whole variant.
+type FieldChange struct {
+	Field     string      `json:"field" bson:"field"`
+	VariantID string      `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
+	Old       interface{} `json:"old" bson:"old"`
+	New       interface{} `json:"new" bson:"new"`
+}
+
+// PriceHistory represents the prices of a product over time, one series for
+// the product and one for each variant
+type PriceHistory struct {
+	ProductID primitive.ObjectID `json:"product_id"`
+	Series    []PriceSeries      `json:"series"`
+}
+
+// PriceSeries represents the price changes of a product or variant. Each
+// price applies from its date until the next point.
+type PriceSeries struct {
+	VariantID string       `json:"variant_id,omitempty"`
+	Name      string       `json:"name"`
+	Points    []PricePoint `json:"points"`
+}
+
+// PricePoint represents a price from a point in time
+type PricePoint struct {
+	Date  time.Time `json:"date"`
+	Price float64   `json:"price"`
+}
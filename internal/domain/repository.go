@@ -12,9 +12,78 @@ type UserRepository interface {
 	Create(ctx context.Context, user *User) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByOIDCSubject(ctx context.Context, issuer, subject string) (*User, error)
 	Update(ctx context.Context, user *User) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
-	List(ctx context.Context, page, limit int) ([]*User, error)
+	List(ctx context.Context, filter UserFilter) ([]*User, error)
+	Count(ctx context.Context, filter UserFilter) (int64, error)
+
+	// Brute-force protection methods
+	IncrementFailedLogins(ctx context.Context, id primitive.ObjectID) (int, error)
+	SetLockedUntil(ctx context.Context, id primitive.ObjectID, lockedUntil time.Time) error
+	ResetFailedLogins(ctx context.Context, id primitive.ObjectID) error
+
+	// Two-factor authentication methods
+	UseMFAStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error)
+	ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error)
+	SetRecoveryCodes(ctx context.Context, id primitive.ObjectID, codeHashes []string) error
+	ListWithPlaintextMFASecrets(ctx context.Context, encryptedPrefix string) ([]*User, error)
+	MarkLegacyEmailsVerified(ctx context.Context) (int64, error)
+}
+
+// FailedLoginRepository defines the interface for failed login data operations
+type FailedLoginRepository interface {
+	Create(ctx context.Context, attempt *FailedLogin) error
+	CountByIP(ctx context.Context, ipAddress string, since time.Time) (int64, error)
+	List(ctx context.Context, filter FailedLoginFilter) ([]*FailedLogin, error)
+	Count(ctx context.Context, filter FailedLoginFilter) (int64, error)
+}
+
+// RoleRepository defines the interface for role data operations
+type RoleRepository interface {
+	Create(ctx context.Context, role *Role) error
+	GetByName(ctx context.Context, name string) (*Role, error)
+	List(ctx context.Context) ([]*Role, error)
+	Update(ctx context.Context, role *Role) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// TokenRepository defines the interface for refresh token, revocation and one-time token data operations
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id primitive.ObjectID, replacedBy *primitive.ObjectID) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID primitive.ObjectID) error
+	ListActiveRefreshTokens(ctx context.Context, userID primitive.ObjectID, limit int) ([]*RefreshToken, error)
+
+	// Access token revocation methods
+	RevokeAccessToken(ctx context.Context, token *RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+
+	// One-time user token methods
+	CreateUserToken(ctx context.Context, token *UserToken) error
+	GetUserTokenByHash(ctx context.Context, purpose, tokenHash string) (*UserToken, error)
+	MarkUserTokenUsed(ctx context.Context, id primitive.ObjectID) (bool, error)
+	DeleteUserTokens(ctx context.Context, userID primitive.ObjectID, purpose string) error
+}
+
+// OIDCStateRepository defines the interface for pending OpenID Connect login data operations
+type OIDCStateRepository interface {
+	Create(ctx context.Context, state *OIDCState) error
+	Consume(ctx context.Context, stateHash string) (*OIDCState, error)
+}
+
+// APIKeyRepository defines the interface for API key data operations
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*APIKey, error)
+	GetByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	List(ctx context.Context, filter APIKeyFilter) ([]*APIKey, error)
+	Count(ctx context.Context, filter APIKeyFilter) (int64, error)
+	Revoke(ctx context.Context, id primitive.ObjectID) error
+	RevokeByUser(ctx context.Context, userID primitive.ObjectID) error
+	TouchLastUsed(ctx context.Context, id primitive.ObjectID, ipAddress string, interval time.Duration) error
 }
 
 // ProductRepository defines the interface for product data operations
@@ -23,15 +92,46 @@ type ProductRepository interface {
 	GetByID(ctx context.Context, id primitive.ObjectID) (*Product, error)
 	Update(ctx context.Context, product *Product) error
 	Delete(ctx context.Context, id primitive.ObjectID) error
+	SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error
+	Restore(ctx context.Context, id primitive.ObjectID) error
+	GetDeletedByID(ctx context.Context, id primitive.ObjectID) (*Product, error)
+	ListDeletedBefore(ctx context.Context, before time.Time) ([]*Product, error)
 	List(ctx context.Context, filter ProductFilter) ([]*Product, error)
+	ListWithCursor(ctx context.Context, filter ProductFilter) ([]*Product, string, error)
+	ForEach(ctx context.Context, filter ProductFilter, fn func(*Product) error) error
 	Count(ctx context.Context, filter ProductFilter) (int64, error)
+	ListByCodes(ctx context.Context, codes []string) ([]*Product, error)
+	ListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*Product, error)
+	ListByName(ctx context.Context, name string) ([]*Product, error)
+	GetFacets(ctx context.Context, filter ProductFilter) (*ProductFacets, error)
+	IncrementSoldCount(ctx context.Context, id primitive.ObjectID, quantity int) error
+	BackfillSoldCounts(ctx context.Context) (int64, error)
+	CountBySpec(ctx context.Context, key string, values []interface{}) (int64, error)
+	RenameCategory(ctx context.Context, from, to string) (int64, error)
 
 	// Stock management methods
-	UpdateStock(ctx context.Context, id primitive.ObjectID, stock int) error
+	UpdateStock(ctx context.Context, id primitive.ObjectID, version int64, stock int) error
+	UpdateVariantStock(ctx context.Context, id primitive.ObjectID, version int64, variantID string, stock int) error
 	GetLowStockProducts(ctx context.Context, threshold int) ([]*LowStockProduct, error)
 	GetStockSummary(ctx context.Context) (*StockSummary, error)
 }
 
+// ProductRevisionRepository defines the interface for product history data operations
+type ProductRevisionRepository interface {
+	Create(ctx context.Context, revision *ProductRevision) error
+	ListByProduct(ctx context.Context, productID primitive.ObjectID, page, limit int) ([]*ProductRevision, error)
+	CountByProduct(ctx context.Context, productID primitive.ObjectID) (int64, error)
+	ListByFields(ctx context.Context, productID primitive.ObjectID, fields []string) ([]*ProductRevision, error)
+}
+
+// CompatibilityRepository defines the interface for part compatibility data operations
+type CompatibilityRepository interface {
+	Apply(ctx context.Context, set []*PartCompatibility, remove []CompatibilityMapping) (int64, error)
+	ListByMachine(ctx context.Context, machineID primitive.ObjectID) ([]*PartCompatibility, error)
+	ListByPart(ctx context.Context, partID primitive.ObjectID) ([]*PartCompatibility, error)
+	DeleteByProduct(ctx context.Context, productID primitive.ObjectID) error
+}
+
 // CategoryRepository defines the interface for category data operations
 type CategoryRepository interface {
 	Create(ctx context.Context, category *Category) error
@@ -42,11 +142,22 @@ type CategoryRepository interface {
 	Delete(ctx context.Context, id primitive.ObjectID) error
 }
 
+// SpecAttributeRepository defines the interface for spec attribute data operations
+type SpecAttributeRepository interface {
+	Create(ctx context.Context, attribute *SpecAttribute) error
+	GetByID(ctx context.Context, id primitive.ObjectID) (*SpecAttribute, error)
+	GetByKey(ctx context.Context, key string) (*SpecAttribute, error)
+	List(ctx context.Context) ([]*SpecAttribute, error)
+	Update(ctx context.Context, attribute *SpecAttribute) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
 // SaleRepository defines the interface for sale data operations
 type SaleRepository interface {
 	Create(ctx context.Context, sale *Sale) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*Sale, error)
 	List(ctx context.Context, filter SaleFilter) ([]*Sale, error)
+	ListWithCursor(ctx context.Context, filter SaleFilter) ([]*Sale, string, error)
 	Count(ctx context.Context, filter SaleFilter) (int64, error)
 
 	// Sales analytics methods
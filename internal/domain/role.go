@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Permissions that can be granted to a role
+const (
+	PermissionAll             = "*" // Grants every permission
+	PermissionProductsWrite   = "products:write"
+	PermissionCategoriesWrite = "categories:write"
+	PermissionInventoryView   = "inventory:view"
+	PermissionInventoryAdjust = "inventory:adjust"
+	PermissionSalesCreate     = "sales:create"
+	PermissionSalesView       = "sales:view"
+	PermissionReportsView     = "reports:view"
+	PermissionUsersManage     = "users:manage"
+	PermissionRolesManage     = "roles:manage"
+	PermissionAPIKeysManage   = "api_keys:manage"
+)
+
+// AllPermissions lists every permission known to the system
+var AllPermissions = []string{
+	PermissionProductsWrite,
+	PermissionCategoriesWrite,
+	PermissionInventoryView,
+	PermissionInventoryAdjust,
+	PermissionSalesCreate,
+	PermissionSalesView,
+	PermissionReportsView,
+	PermissionUsersManage,
+	PermissionRolesManage,
+	PermissionAPIKeysManage,
+}
+
+// Built-in roles
+const (
+	RoleAdmin          = "admin"
+	RoleManager        = "manager"
+	RoleInventoryClerk = "inventory_clerk"
+	RoleCashier        = "cashier"
+	RoleUser           = "user" // Least-privileged role given to self-registered accounts
+)
+
+// Role represents a named set of permissions
+type Role struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description" bson:"description"`
+	Permissions []string           `json:"permissions" bson:"permissions"`
+	IsSystem    bool               `json:"is_system" bson:"is_system"` // Built-in roles cannot be deleted
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// CreateRoleRequest represents the request payload for creating a role
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// UpdateRoleRequest represents the request payload for updating a role
+type UpdateRoleRequest struct {
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// DefaultRoles returns the built-in roles created on startup
+func DefaultRoles() []Role {
+	return []Role{
+		{
+			Name:        RoleAdmin,
+			Description: "Full access to the system",
+			Permissions: []string{PermissionAll},
+		},
+		{
+			Name:        RoleManager,
+			Description: "Manages the catalog, stock and sales and views reports",
+			Permissions: []string{
+				PermissionProductsWrite,
+				PermissionCategoriesWrite,
+				PermissionInventoryView,
+				PermissionInventoryAdjust,
+				PermissionSalesCreate,
+				PermissionSalesView,
+				PermissionReportsView,
+			},
+		},
+		{
+			Name:        RoleInventoryClerk,
+			Description: "Views and adjusts stock levels",
+			Permissions: []string{
+				PermissionInventoryView,
+				PermissionInventoryAdjust,
+			},
+		},
+		{
+			Name:        RoleCashier,
+			Description: "Records sales at the counter",
+			Permissions: []string{
+				PermissionSalesCreate,
+				PermissionSalesView,
+				PermissionInventoryView,
+			},
+		},
+		{
+			Name:        RoleUser,
+			Description: "Registered customer without staff access",
+			Permissions: []string{},
+		},
+	}
+}
+
+// IsValidPermission checks whether a permission is known to the system
+func IsValidPermission(permission string) bool {
+	if permission == PermissionAll {
+		return true
+	}
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPermission checks whether the granted permissions include the required one
+func HasPermission(granted []string, required string) bool {
+	for _, p := range granted {
+		if p == PermissionAll || p == required {
+			return true
+		}
+	}
+	return false
+}
@@ -8,22 +8,26 @@ import (
 
 // Sale represents a sale transaction
 type Sale struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	ProductID primitive.ObjectID `json:"product_id" bson:"product_id"`
-	Product   *Product           `json:"product,omitempty" bson:"product,omitempty"`
-	Quantity  int                `json:"quantity" bson:"quantity"`
-	Price     float64            `json:"price" bson:"price"`
-	Total     float64            `json:"total" bson:"total"`
-	DateSold  time.Time          `json:"date_sold" bson:"date_sold"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ProductID   primitive.ObjectID `json:"product_id" bson:"product_id"`
+	Product     *Product           `json:"product,omitempty" bson:"product,omitempty"`
+	ProductName string             `json:"product_name,omitempty" bson:"product_name,omitempty"` // Name at the time of sale, kept for reports after the product is purged
+	VariantID   string             `json:"variant_id,omitempty" bson:"variant_id,omitempty"`
+	VariantSKU  string             `json:"variant_sku,omitempty" bson:"variant_sku,omitempty"`
+	Quantity    int                `json:"quantity" bson:"quantity"`
+	Price       float64            `json:"price" bson:"price"`
+	Total       float64            `json:"total" bson:"total"`
+	DateSold    time.Time          `json:"date_sold" bson:"date_sold"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
 }
 
 // CreateSaleRequest represents the request payload for creating a sale
 type CreateSaleRequest struct {
 	ProductID primitive.ObjectID `json:"product_id" binding:"required"`
+	VariantID string             `json:"variant_id"` // Required for products with variants
 	Quantity  int                `json:"quantity" binding:"required,gt=0"`
-	Price     float64            `json:"price" binding:"required,gt=0"`
+	Price     float64            `json:"price" binding:"omitempty,gt=0"` // Unit price, the price of the product or variant when left out
 }
 
 // SaleFilter represents filter options for sales
@@ -33,6 +37,14 @@ type SaleFilter struct {
 	ToDate    time.Time          `json:"to_date"`
 	Page      int                `json:"page"`
 	Limit     int                `json:"limit"`
+
+	// Sort is date_sold (default), total or quantity, Order is asc or desc (default)
+	Sort  string `json:"sort"`
+	Order string `json:"order"`
+
+	// Cursor switches from Page to keyset pagination. An empty cursor
+	// requests the first page, the next_cursor of a page the one after it.
+	Cursor *string `json:"cursor"`
 }
 
 // SalesSummary represents sales summary data
@@ -45,15 +57,17 @@ type SalesSummary struct {
 
 // ProductSales represents sales data for a specific product
 type ProductSales struct {
-	ProductID    primitive.ObjectID `json:"product_id"`
-	ProductName  string             `json:"product_name"`
-	TotalSold    int                `json:"total_sold"`
-	TotalRevenue float64            `json:"total_revenue"`
+	ProductID      primitive.ObjectID `json:"product_id" bson:"product_id"`
+	ProductName    string             `json:"product_name" bson:"product_name"`
+	ProductDeleted bool               `json:"product_deleted" bson:"product_deleted"` // The product is in the trash or purged
+	TotalSold      int                `json:"total_sold" bson:"total_sold"`
+	TotalRevenue   float64            `json:"total_revenue" bson:"total_revenue"`
 }
 
 // StockUpdateRequest represents the request payload for updating stock
 type StockUpdateRequest struct {
-	Stock int `json:"stock" binding:"required,gte=0"`
+	VariantID string `json:"variant_id"` // Required for products with variants
+	Stock     int    `json:"stock" binding:"required,gte=0"`
 }
 
 // StockSummary represents stock summary data
@@ -66,17 +80,22 @@ type StockSummary struct {
 
 // CategoryStock represents stock data for a category
 type CategoryStock struct {
-	Category     string  `json:"category"`
-	TotalStock   int     `json:"total_stock"`
-	TotalValue   float64 `json:"total_value"`
-	ProductCount int     `json:"product_count"`
+	Category     string  `json:"category" bson:"category"`
+	TotalStock   int     `json:"total_stock" bson:"total_stock"`
+	TotalValue   float64 `json:"total_value" bson:"total_value"`
+	ProductCount int     `json:"product_count" bson:"product_count"`
 }
 
 // LowStockProduct represents a product with low stock
+// or, for products with variants, a variant with low stock
 type LowStockProduct struct {
-	ID       primitive.ObjectID `json:"id"`
-	Name     string             `json:"name"`
-	Stock    int                `json:"stock"`
-	Category string             `json:"category"`
-	Price    float64            `json:"price"`
+	ID          primitive.ObjectID `json:"id"`
+	SKU         string             `json:"sku,omitempty"`
+	Name        string             `json:"name"`
+	VariantID   string             `json:"variant_id,omitempty"`
+	VariantSKU  string             `json:"variant_sku,omitempty"`
+	VariantName string             `json:"variant_name,omitempty"`
+	Stock       int                `json:"stock"`
+	Category    string             `json:"category"`
+	Price       float64            `json:"price"`
 }
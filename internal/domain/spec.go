@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Spec attribute types
+const (
+	SpecTypeNumber  = "number"  // Measured value in the unit of the attribute, e.g. 45 hp
+	SpecTypeEnum    = "enum"    // One of the values of the attribute, e.g. diesel
+	SpecTypeBoolean = "boolean" // Feature a product has or not, e.g. four-wheel drive
+)
+
+// SpecAttribute defines a technical specification products can have, such as
+// horsepower or fuel type. The key is used in product specs and as the
+// product list filter parameter.
+type SpecAttribute struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Key       string             `json:"key" bson:"key"`   // e.g. hp, filtered with hp=45, hp_min=30 and hp_max=60
+	Name      string             `json:"name" bson:"name"` // Display name, e.g. Engine power
+	Type      string             `json:"type" bson:"type"`
+	Unit      string             `json:"unit,omitempty" bson:"unit,omitempty"`     // Unit of number attributes, e.g. hp or m
+	Values    []string           `json:"values,omitempty" bson:"values,omitempty"` // Allowed values of enum attributes
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+}
+
+// ProductSpec represents the value of a spec attribute for a product. Value
+// is a number, a string or a boolean depending on the attribute type.
+type ProductSpec struct {
+	Key   string      `json:"key" bson:"key" binding:"required"`
+	Value interface{} `json:"value" bson:"value"`
+	Unit  string      `json:"unit,omitempty" bson:"unit,omitempty"` // Copied from the attribute
+}
+
+// SpecFilter represents a filter on a spec attribute. A product matches when
+// its value is one of Values and within Min and Max.
+type SpecFilter struct {
+	Key    string        `json:"key"`
+	Values []interface{} `json:"values,omitempty"`
+	Min    *float64      `json:"min,omitempty"`
+	Max    *float64      `json:"max,omitempty"`
+}
+
+// CreateSpecAttributeRequest represents the request payload for creating a spec attribute
+type CreateSpecAttributeRequest struct {
+	Key    string   `json:"key" binding:"required"`
+	Name   string   `json:"name" binding:"required"`
+	Type   string   `json:"type" binding:"required,oneof=number enum boolean"`
+	Unit   string   `json:"unit"`
+	Values []string `json:"values"` // Required for enum attributes
+}
+
+// UpdateSpecAttributeRequest represents the request payload for updating a
+// spec attribute. The key, type and unit cannot be changed since products
+// store values for them.
+type UpdateSpecAttributeRequest struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"` // Replaces the enum values when given
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken represents a long-lived token used to obtain new access tokens
+type RefreshToken struct {
+	ID               primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID           primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	FamilyID         string              `json:"family_id" bson:"family_id"` // Shared by every token rotated from the same login
+	TokenHash        string              `json:"-" bson:"token_hash"`        // SHA-256 of the token, the token itself is never stored
+	IPAddress        string              `json:"ip_address" bson:"ip_address"`
+	UserAgent        string              `json:"user_agent" bson:"user_agent"`
+	SessionStartedAt time.Time           `json:"session_started_at" bson:"session_started_at"`
+	ExpiresAt        time.Time           `json:"expires_at" bson:"expires_at"`
+	RevokedAt        *time.Time          `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+	ReplacedBy       *primitive.ObjectID `json:"-" bson:"replaced_by,omitempty"`
+	CreatedAt        time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// RevokedToken represents an access token that was revoked before it expired
+type RevokedToken struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	JTI       string             `json:"jti" bson:"jti"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"` // Removed by a TTL index once the token would have expired anyway
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// RefreshTokenRequest represents the request payload for refreshing an access token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	IPAddress    string `json:"-"`
+	UserAgent    string `json:"-"`
+}
+
+// LogoutRequest represents the request payload for logging out
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"` // Ends the session this refresh token belongs to
+	AllSessions  bool   `json:"all_sessions"`  // Ends every session of the user
+}
+
+// Purposes of one-time user tokens
+const (
+	TokenPurposePasswordReset = "password_reset"
+	TokenPurposeEmailChange   = "email_change"
+
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// UserToken represents a one-time token sent to a user, such as a password reset link
+type UserToken struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Purpose   string             `json:"purpose" bson:"purpose"`
+	TokenHash string             `json:"-" bson:"token_hash"` // SHA-256 of the token, the token itself is only sent to the user
+	ExpiresAt time.Time          `json:"expires_at" bson:"expires_at"`
+	UsedAt    *time.Time         `json:"used_at,omitempty" bson:"used_at,omitempty"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// JSONWebKey represents a public signing key in JWK format (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JSONWebKeySet represents the set of public keys that verify our tokens
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
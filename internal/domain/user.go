@@ -16,6 +16,28 @@ type User struct {
 	IsActive  bool               `json:"is_active" bson:"is_active"`
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty" bson:"password_changed_at,omitempty"`
+	EmailVerifiedAt   *time.Time `json:"email_verified_at" bson:"email_verified_at"`   // Null until the user follows the verification link
+	PendingEmail      string     `json:"pending_email,omitempty" bson:"pending_email"` // New email waiting to be confirmed from a link sent to it
+
+	// Account at the OpenID Connect provider linked to the user
+	OIDCIssuer  string `json:"oidc_issuer,omitempty" bson:"oidc_issuer,omitempty"`
+	OIDCSubject string `json:"-" bson:"oidc_subject,omitempty"`
+	OIDCManaged bool   `json:"-" bson:"oidc_managed,omitempty"` // Provisioned through the provider, so the role follows its groups
+
+	// Brute-force protection
+	FailedLoginAttempts int        `json:"failed_login_attempts" bson:"failed_login_attempts"`
+	LastFailedLoginAt   *time.Time `json:"last_failed_login_at,omitempty" bson:"last_failed_login_at,omitempty"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty" bson:"locked_until,omitempty"` // Login is refused until this time
+
+	// Two-factor authentication
+	MFAEnabled       bool       `json:"mfa_enabled" bson:"mfa_enabled"`
+	MFAEnabledAt     *time.Time `json:"mfa_enabled_at,omitempty" bson:"mfa_enabled_at,omitempty"`
+	MFASecret        string     `json:"-" bson:"mfa_secret"`                   // Encrypted, see utils.EncryptSecret
+	MFAPendingSecret string     `json:"-" bson:"mfa_pending_secret"`           // Secret being enrolled, until confirmed with a code
+	MFALastUsedStep  int64      `json:"-" bson:"mfa_last_used_step,omitempty"` // Time step of the last accepted code, codes cannot be reused
+	MFARecoveryCodes []string   `json:"-" bson:"mfa_recovery_codes"`           // SHA-256 hashes of the unused recovery codes
 }
 
 // CreateUserRequest represents the request payload for creating a user
@@ -23,17 +45,156 @@ type CreateUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required"`
-	Role     string `json:"role"`
+}
+
+// AdminCreateUserRequest represents the request payload for an admin creating a staff account
+type AdminCreateUserRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6"`
+	Name     string `json:"name" binding:"required"`
+	Role     string `json:"role" binding:"required"`
 }
 
 // LoginRequest represents the request payload for user login
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 // LoginResponse represents the response payload for successful login
 type LoginResponse struct {
-	Token string `json:"token"`
-	User  User   `json:"user"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+	User             User      `json:"user"`
+	MFASetupRequired bool      `json:"mfa_setup_required,omitempty"` // The role requires MFA; only MFA enrollment is allowed until it is enabled
+}
+
+// MFAChallengeResponse represents the response payload when a login needs a second factor
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"` // Exchanged for the real tokens at /auth/mfa/verify
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// MFAVerifyRequest represents the request payload for completing a login with a second factor
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`          // Code from the authenticator app
+	RecoveryCode string `json:"recovery_code"` // Used instead of a code when the authenticator is lost
+	IPAddress    string `json:"-"`
+	UserAgent    string `json:"-"`
+}
+
+// MFASetupResponse represents the response payload for starting MFA enrollment
+type MFASetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to render as a QR code
+}
+
+// MFACodeRequest represents a request payload confirmed with an authenticator code
+type MFACodeRequest struct {
+	Code      string `json:"code" binding:"required"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// MFADisableRequest represents the request payload for disabling MFA
+type MFADisableRequest struct {
+	Password  string `json:"password" binding:"required"`
+	Code      string `json:"code" binding:"required"`
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// MFARecoveryCodesResponse represents the response payload containing new recovery codes
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // Only shown once
+}
+
+// ProfileResponse represents the account settings of the current user
+type ProfileResponse struct {
+	User
+	Permissions []string   `json:"permissions"`
+	Sessions    []*Session `json:"sessions"` // Active login sessions, most recently used first
+}
+
+// Session represents an active login session of a user
+type Session struct {
+	ID         string    `json:"id"` // Family ID of the session's refresh tokens
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	StartedAt  time.Time `json:"started_at"`
+	LastUsedAt time.Time `json:"last_used_at"` // Last login or token refresh
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// UpdateProfileRequest represents the request payload for updating the current user.
+// Empty fields are left unchanged.
+type UpdateProfileRequest struct {
+	Name            string `json:"name"`
+	Email           string `json:"email" binding:"omitempty,email"` // Only applied once confirmed from the new address
+	CurrentPassword string `json:"current_password"`                // Required to change the email
+}
+
+// ConfirmEmailChangeRequest represents the request payload for confirming a new email address
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmailRequest represents the request payload for verifying an email address
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResendVerificationRequest represents the request payload for requesting a new verification email
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// DeleteAccountRequest represents the request payload for closing the current user's account
+type DeleteAccountRequest struct {
+	Password  string `json:"password" binding:"required"`
+	Code      string `json:"code"` // Authenticator code, required when MFA is enabled
+	IPAddress string `json:"-"`
+	UserAgent string `json:"-"`
+}
+
+// UserFilter represents filter options for users
+type UserFilter struct {
+	Search   string `json:"search"` // Matches name or email
+	Role     string `json:"role"`
+	IsActive *bool  `json:"is_active"`
+	Page     int    `json:"page"`
+	Limit    int    `json:"limit"`
+}
+
+// UpdateUserStatusRequest represents the request payload for activating or deactivating a user
+type UpdateUserStatusRequest struct {
+	IsActive *bool `json:"is_active" binding:"required"`
+}
+
+// UpdateUserRoleRequest represents the request payload for changing a user's role
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// ForgotPasswordRequest represents the request payload for requesting a password reset
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents the request payload for resetting a password with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// ChangePasswordRequest represents the request payload for changing the password of the current user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=6"`
 }
@@ -10,6 +10,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// productTextIndexName is the name of the text index used by product search
+const productTextIndexName = "product_text_search"
+
 // MongoDB represents MongoDB connection
 type MongoDB struct {
 	client   *mongo.Client
@@ -70,7 +73,7 @@ func (m *MongoDB) CreateIndexes() error {
 		Options: options.Index().SetUnique(true),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	_, err := userCollection.Indexes().CreateOne(ctx, userIndexModel)
@@ -78,11 +81,33 @@ func (m *MongoDB) CreateIndexes() error {
 		return err
 	}
 
-	// Create indexes for products
+	// An account at the identity provider can only be linked to one user
+	_, err = userCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "oidc_issuer", Value: 1}, {Key: "oidc_subject", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+			"oidc_subject": bson.M{"$exists": true},
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Create indexes for products. A collection can only have one text
+	// index, so an older one with different fields is replaced.
 	productCollection := m.GetCollection("products")
+	err = dropTextIndexesExcept(ctx, productCollection, productTextIndexName)
+	if err != nil {
+		return err
+	}
+
 	productIndexes := []mongo.IndexModel{
 		{
-			Keys: bson.D{{"name", "text"}, {"description", "text"}},
+			Keys: bson.D{{Key: "name", Value: "text"}, {Key: "brand", Value: "text"}, {Key: "description", Value: "text"}},
+			Options: options.Index().SetName(productTextIndexName).SetWeights(bson.D{
+				{Key: "name", Value: 10},
+				{Key: "brand", Value: 5},
+				{Key: "description", Value: 1},
+			}),
 		},
 		{
 			Keys: bson.D{{"category", 1}},
@@ -93,6 +118,53 @@ func (m *MongoDB) CreateIndexes() error {
 		{
 			Keys: bson.D{{"price", 1}},
 		},
+		{
+			Keys: bson.D{{Key: "variants.price", Value: 1}},
+		},
+		// SKUs and barcodes identify a single product or variant at the
+		// counter. Barcodes are only indexed where they are strings, so
+		// products and variants without barcodes do not share a null key.
+		{
+			Keys:    bson.D{{Key: "sku", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"sku": bson.M{"$gt": ""}}),
+		},
+		{
+			Keys:    bson.D{{Key: "barcodes.code", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"barcodes.code": bson.M{"$type": "string"}}),
+		},
+		{
+			Keys:    bson.D{{Key: "variants.sku", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"variants.sku": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "variants.barcodes.code", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"variants.barcodes.code": bson.M{"$type": "string"}}),
+		},
+		// The trash is purged by deletion time
+		{
+			Keys:    bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"deleted_at": bson.M{"$exists": true}}),
+		},
+		// Spec filters match key and value within one spec
+		{
+			Keys: bson.D{{Key: "specs.key", Value: 1}, {Key: "specs.value", Value: 1}},
+		},
+		// Sort orders of product lists, with _id as the cursor tie-breaker
+		{
+			Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "stock", Value: 1}, {Key: "_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "sold_count", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "price", Value: 1}, {Key: "_id", Value: 1}},
+		},
 	}
 
 	_, err = productCollection.Indexes().CreateMany(ctx, productIndexes)
@@ -100,6 +172,228 @@ func (m *MongoDB) CreateIndexes() error {
 		return err
 	}
 
+	// Create indexes for sales, sorted by date with _id as the cursor tie-breaker
+	saleCollection := m.GetCollection("sales")
+	saleIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "date_sold", Value: -1}, {Key: "_id", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "date_sold", Value: -1}},
+		},
+	}
+
+	_, err = saleCollection.Indexes().CreateMany(ctx, saleIndexes)
+	if err != nil {
+		return err
+	}
+
+	// Create indexes for product history
+	productRevisionCollection := m.GetCollection("product_revisions")
+	productRevisionIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "product_id", Value: 1}, {Key: "changes.field", Value: 1}},
+		},
+	}
+
+	_, err = productRevisionCollection.Indexes().CreateMany(ctx, productRevisionIndexes)
+	if err != nil {
+		return err
+	}
+
+	// Create indexes for part compatibility, looked up from both the part
+	// and the machine. A part is mapped to a machine at most once.
+	compatibilityCollection := m.GetCollection("part_compatibility")
+	compatibilityIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "part_id", Value: 1}, {Key: "machine_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "machine_id", Value: 1}},
+		},
+	}
+
+	_, err = compatibilityCollection.Indexes().CreateMany(ctx, compatibilityIndexes)
+	if err != nil {
+		return err
+	}
+
+	// Create unique index for spec attribute keys
+	specAttributeCollection := m.GetCollection("spec_attributes")
+	_, err = specAttributeCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "key", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Create unique index for role name
+	roleCollection := m.GetCollection("roles")
+	roleIndexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err = roleCollection.Indexes().CreateOne(ctx, roleIndexModel)
+	if err != nil {
+		return err
+	}
+
+	// Create indexes for refresh tokens, expired tokens are removed by the TTL index
+	refreshTokenCollection := m.GetCollection("refresh_tokens")
+	refreshTokenIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "family_id", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err = refreshTokenCollection.Indexes().CreateMany(ctx, refreshTokenIndexes)
+	if err != nil {
+		return err
+	}
+
+	// Create indexes for revoked access tokens
+	revokedTokenCollection := m.GetCollection("revoked_tokens")
+	revokedTokenIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "jti", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err = revokedTokenCollection.Indexes().CreateMany(ctx, revokedTokenIndexes)
+	if err != nil {
+		return err
+	}
+
+	// Create indexes for one-time user tokens
+	userTokenCollection := m.GetCollection("user_tokens")
+	userTokenIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}},
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err = userTokenCollection.Indexes().CreateMany(ctx, userTokenIndexes)
+	if err != nil {
+		return err
+	}
+
+	// Create indexes for failed logins, kept for 30 days as an audit trail
+	failedLoginCollection := m.GetCollection("failed_logins")
+	failedLoginIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "ip_address", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "email", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60),
+		},
+	}
+
+	_, err = failedLoginCollection.Indexes().CreateMany(ctx, failedLoginIndexes)
+	if err != nil {
+		return err
+	}
+
+	// Create indexes for API keys
+	apiKeyCollection := m.GetCollection("api_keys")
+	apiKeyIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}},
+		},
+	}
+
+	_, err = apiKeyCollection.Indexes().CreateMany(ctx, apiKeyIndexes)
+	if err != nil {
+		return err
+	}
+
+	// Create indexes for pending OpenID Connect logins
+	oidcStateCollection := m.GetCollection("oidc_states")
+	oidcStateIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "state_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err = oidcStateCollection.Indexes().CreateMany(ctx, oidcStateIndexes)
+	if err != nil {
+		return err
+	}
+
 	log.Println("Database indexes created successfully!")
 	return nil
 }
+
+// dropTextIndexesExcept drops the text indexes of a collection other than the named one
+func dropTextIndexesExcept(ctx context.Context, collection *mongo.Collection, name string) error {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var indexes []struct {
+		Name string `bson:"name"`
+		Key  bson.M `bson:"key"`
+	}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		// Text indexes are keyed on the internal _fts field
+		if _, ok := index.Key["_fts"]; !ok || index.Name == name {
+			continue
+		}
+		if _, err := collection.Indexes().DropOne(ctx, index.Name); err != nil {
+			return err
+		}
+		log.Printf("Dropped text index %s", index.Name)
+	}
+	return nil
+}
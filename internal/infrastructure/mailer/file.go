@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// unsafeFileChars matches characters that should not appear in file names
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._@-]`)
+
+// fileMailer writes emails to a directory instead of sending them
+type fileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates a mailer that writes every email as an .eml file into dir.
+// It is meant for local development and tests.
+func NewFileMailer(dir, from string) (domain.Mailer, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &fileMailer{dir: dir, from: from}, nil
+}
+
+// Send writes the email to a new file
+func (m *fileMailer) Send(ctx context.Context, msg domain.EmailMessage) error {
+	if err := validateRecipient(msg.To); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d_%s.eml", time.Now().UnixNano(), unsafeFileChars.ReplaceAllString(msg.To, "_"))
+	return os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, msg), 0600)
+}
@@ -0,0 +1,27 @@
+package mailer
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/logger"
+	"context"
+)
+
+// logMailer writes emails to the application log instead of sending them
+type logMailer struct {
+	logger logger.Logger
+}
+
+// NewLogMailer creates a mailer that logs every email. It is meant for local development.
+func NewLogMailer(logger logger.Logger) domain.Mailer {
+	return &logMailer{logger: logger}
+}
+
+// Send logs the email
+func (m *logMailer) Send(ctx context.Context, msg domain.EmailMessage) error {
+	if err := validateRecipient(msg.To); err != nil {
+		return err
+	}
+
+	m.logger.Info("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"fmt"
+	"net/smtp"
+)
+
+// SMTPConfig holds the settings of an SMTP server
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// smtpMailer delivers emails through an SMTP server
+type smtpMailer struct {
+	config SMTPConfig
+}
+
+// NewSMTPMailer creates a mailer that delivers emails through an SMTP server
+func NewSMTPMailer(config SMTPConfig) domain.Mailer {
+	return &smtpMailer{config: config}
+}
+
+// Send delivers an email. The connection is upgraded with STARTTLS when the server supports it.
+func (m *smtpMailer) Send(ctx context.Context, msg domain.EmailMessage) error {
+	if err := validateRecipient(msg.To); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", m.config.Host, m.config.Port)
+	return smtp.SendMail(addr, auth, m.config.From, []string{msg.To}, buildMessage(m.config.From, msg))
+}
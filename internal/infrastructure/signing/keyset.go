@@ -0,0 +1,195 @@
+package signing
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// hmacKeyID is the key ID of the shared secret used when no key files are configured
+const hmacKeyID = "hmac"
+
+// key is a single signing or verification key
+type key struct {
+	id         string
+	method     jwt.SigningMethod
+	signingKey interface{} // Private key or HMAC secret, nil for verification-only keys
+	verifyKey  interface{} // Public key or HMAC secret
+}
+
+// KeySet signs tokens with its active key and verifies tokens signed with any
+// of its keys. Keeping the previous keys in the set allows rotating the
+// active key without invalidating tokens that were already issued.
+type KeySet struct {
+	active *key
+	keys   map[string]*key
+}
+
+// NewHMACKeySet creates a key set that signs and verifies with a shared secret (HS256)
+func NewHMACKeySet(secret string) *KeySet {
+	k := &key{
+		id:         hmacKeyID,
+		method:     jwt.SigningMethodHS256,
+		signingKey: []byte(secret),
+		verifyKey:  []byte(secret),
+	}
+	return &KeySet{active: k, keys: map[string]*key{k.id: k}}
+}
+
+// LoadKeySet loads every PEM file in dir. The file name without extension is
+// the key ID. RSA keys sign with RS256 and Ed25519 keys with EdDSA. Private
+// keys can sign and verify; public keys only verify, which is useful while a
+// retired key's tokens expire. activeKeyID selects the signing key.
+func LoadKeySet(dir, activeKeyID string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .pem keys found in %s", dir)
+	}
+
+	set := &KeySet{keys: make(map[string]*key)}
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		k, err := loadKey(id, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %w", path, err)
+		}
+		set.keys[id] = k
+	}
+
+	active, ok := set.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found in %s", activeKeyID, dir)
+	}
+	if active.signingKey == nil {
+		return nil, fmt.Errorf("active key %q is a public key and cannot sign", activeKeyID)
+	}
+	set.active = active
+
+	return set, nil
+}
+
+// Sign signs the claims with the active key and sets the kid header
+func (s *KeySet) Sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(s.active.method, claims)
+	token.Header["kid"] = s.active.id
+	return token.SignedString(s.active.signingKey)
+}
+
+// Parse verifies the signature of a token with the key named by its kid
+// header and returns its claims. Tokens without a kid are verified with the
+// active key. The algorithm must match the key, so a public key can never be
+// used as an HMAC secret.
+func (s *KeySet) Parse(tokenString string, options ...jwt.ParserOption) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		k := s.active
+		if kid, ok := token.Header["kid"].(string); ok {
+			if k, ok = s.keys[kid]; !ok {
+				return nil, errors.New("unknown signing key")
+			}
+		}
+
+		if token.Method.Alg() != k.method.Alg() {
+			return nil, errors.New("invalid signing method")
+		}
+		return k.verifyKey, nil
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// JWKS returns the public keys of the set. Shared secrets are never published.
+func (s *KeySet) JWKS() domain.JSONWebKeySet {
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := domain.JSONWebKeySet{Keys: []domain.JSONWebKey{}}
+	for _, id := range ids {
+		k := s.keys[id]
+		jwk := domain.JSONWebKey{KeyID: k.id, Use: "sig", Algorithm: k.method.Alg()}
+
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// loadKey reads an RSA or Ed25519 key from a PEM file
+func loadKey(id, path string) (*key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	k := &key{id: id}
+	switch typed := parsed.(type) {
+	case *rsa.PrivateKey:
+		k.method, k.signingKey, k.verifyKey = jwt.SigningMethodRS256, typed, typed.Public()
+	case *rsa.PublicKey:
+		k.method, k.verifyKey = jwt.SigningMethodRS256, typed
+	case ed25519.PrivateKey:
+		k.method, k.signingKey, k.verifyKey = jwt.SigningMethodEdDSA, typed, typed.Public()
+	case ed25519.PublicKey:
+		k.method, k.verifyKey = jwt.SigningMethodEdDSA, typed
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+
+	return k, nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// apiKeyRepository implements domain.APIKeyRepository
+type apiKeyRepository struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *database.MongoDB) domain.APIKeyRepository {
+	return &apiKeyRepository{
+		db:         db,
+		collection: db.GetCollection("api_keys"),
+	}
+}
+
+// Create creates a new API key
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	key.ID = primitive.NewObjectID()
+	key.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, key)
+	return err
+}
+
+// GetByID retrieves an API key by ID
+func (r *apiKeyRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*domain.APIKey, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+// GetByHash retrieves an API key by the hash of the key
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	return r.findOne(ctx, bson.M{"key_hash": keyHash})
+}
+
+// List retrieves API keys with filtering and pagination
+func (r *apiKeyRepository) List(ctx context.Context, filter domain.APIKeyFilter) ([]*domain.APIKey, error) {
+	opts := options.Find()
+
+	// Pagination
+	if filter.Page > 0 && filter.Limit > 0 {
+		skip := (filter.Page - 1) * filter.Limit
+		opts.SetSkip(int64(skip))
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, buildAPIKeyFilter(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var keys []*domain.APIKey
+	for cursor.Next(ctx) {
+		var key domain.APIKey
+		if err := cursor.Decode(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+
+	return keys, cursor.Err()
+}
+
+// Count returns the total count of API keys matching the filter
+func (r *apiKeyRepository) Count(ctx context.Context, filter domain.APIKeyFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, buildAPIKeyFilter(filter))
+}
+
+// Revoke revokes an API key
+func (r *apiKeyRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// RevokeByUser revokes every active API key that acts as a user
+func (r *apiKeyRepository) RevokeByUser(ctx context.Context, userID primitive.ObjectID) error {
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	_, err := r.collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// TouchLastUsed records that an API key was used. To avoid a write on every
+// request the timestamp is only updated once per interval.
+func (r *apiKeyRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, ipAddress string, interval time.Duration) error {
+	now := time.Now()
+	filter := bson.M{
+		"_id": id,
+		"$or": []bson.M{
+			{"last_used_at": bson.M{"$exists": false}},
+			{"last_used_at": bson.M{"$lt": now.Add(-interval)}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"last_used_at": now, "last_used_ip": ipAddress}}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// findOne retrieves a single API key matching the filter
+func (r *apiKeyRepository) findOne(ctx context.Context, filter bson.M) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.collection.FindOne(ctx, filter).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// buildAPIKeyFilter builds the MongoDB filter for an API key filter
+func buildAPIKeyFilter(filter domain.APIKeyFilter) bson.M {
+	mongoFilter := bson.M{}
+
+	if filter.UserID != nil {
+		mongoFilter["user_id"] = *filter.UserID
+	}
+	if !filter.IncludeRevoked {
+		mongoFilter["revoked_at"] = bson.M{"$exists": false}
+	}
+
+	return mongoFilter
+}
@@ -38,6 +38,7 @@ func (r *categoryRepository) Create(ctx context.Context, category *domain.Catego
 	}
 
 	category.ID = primitive.NewObjectID()
+	category.Version = 1
 	category.CreatedAt = time.Now()
 	category.UpdatedAt = time.Now()
 
@@ -91,15 +92,28 @@ func (r *categoryRepository) List(ctx context.Context) ([]*domain.Category, erro
 	return categories, cursor.Err()
 }
 
-// Update updates a category
+// Update updates a category if it is still at the version it was read at
 func (r *categoryRepository) Update(ctx context.Context, category *domain.Category) error {
 	category.UpdatedAt = time.Now()
 
-	filter := bson.M{"_id": category.ID}
-	update := bson.M{"$set": category}
+	filter := bson.M{"_id": category.ID, "version": versionFilter(category.Version)}
+	update := bson.M{
+		"$set": bson.M{
+			"name":       category.Name,
+			"updated_at": category.UpdatedAt,
+		},
+		"$inc": bson.M{"version": 1},
+	}
 
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errVersionMismatch
+	}
+	category.Version++
+	return nil
 }
 
 // Delete deletes a category
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// compatibilityRepository implements domain.CompatibilityRepository
+type compatibilityRepository struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewCompatibilityRepository creates a new part compatibility repository
+func NewCompatibilityRepository(db *database.MongoDB) domain.CompatibilityRepository {
+	return &compatibilityRepository{
+		db:         db,
+		collection: db.GetCollection("part_compatibility"),
+	}
+}
+
+// Apply sets and removes compatibility mappings in a single bulk write and
+// returns the number of mappings removed. Setting a part and machine pair
+// that is already mapped replaces its fitment.
+func (r *compatibilityRepository) Apply(ctx context.Context, set []*domain.PartCompatibility, remove []domain.CompatibilityMapping) (int64, error) {
+	now := time.Now()
+
+	models := make([]mongo.WriteModel, 0, len(set)+len(remove))
+	for _, compatibility := range set {
+		compatibility.UpdatedAt = now
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"part_id": compatibility.PartID, "machine_id": compatibility.MachineID}).
+			SetUpdate(bson.M{
+				"$set": bson.M{
+					"year_from":   compatibility.YearFrom,
+					"year_to":     compatibility.YearTo,
+					"serial_from": compatibility.SerialFrom,
+					"serial_to":   compatibility.SerialTo,
+					"notes":       compatibility.Notes,
+					"updated_at":  now,
+				},
+				"$setOnInsert": bson.M{"created_at": now},
+			}).
+			SetUpsert(true))
+	}
+	for _, mapping := range remove {
+		models = append(models, mongo.NewDeleteOneModel().
+			SetFilter(bson.M{"part_id": mapping.PartID, "machine_id": mapping.MachineID}))
+	}
+	if len(models) == 0 {
+		return 0, nil
+	}
+
+	result, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+// ListByMachine retrieves the mappings of the parts that fit a machine
+func (r *compatibilityRepository) ListByMachine(ctx context.Context, machineID primitive.ObjectID) ([]*domain.PartCompatibility, error) {
+	return r.find(ctx, bson.M{"machine_id": machineID})
+}
+
+// ListByPart retrieves the mappings of the machines a part fits
+func (r *compatibilityRepository) ListByPart(ctx context.Context, partID primitive.ObjectID) ([]*domain.PartCompatibility, error) {
+	return r.find(ctx, bson.M{"part_id": partID})
+}
+
+// DeleteByProduct deletes the mappings of a product, as a part and as a machine
+func (r *compatibilityRepository) DeleteByProduct(ctx context.Context, productID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"part_id": productID},
+		bson.M{"machine_id": productID},
+	}})
+	return err
+}
+
+func (r *compatibilityRepository) find(ctx context.Context, filter bson.M) ([]*domain.PartCompatibility, error) {
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var mappings []*domain.PartCompatibility
+	for cursor.Next(ctx) {
+		var mapping domain.PartCompatibility
+		if err := cursor.Decode(&mapping); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, &mapping)
+	}
+
+	return mappings, cursor.Err()
+}
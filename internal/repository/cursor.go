@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"encoding/base64"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errInvalidCursor is returned for cursors that cannot be decoded or were
+// created for a different sort
+var errInvalidCursor = errors.New("invalid cursor")
+
+// sortSpec sorts on a field, with _id breaking ties so every document has a
+// unique position for keyset pagination
+type sortSpec struct {
+	Field string
+	Order int // 1 ascending, -1 descending
+}
+
+// document returns the MongoDB sort document
+func (s sortSpec) document() bson.D {
+	return bson.D{{Key: s.Field, Value: s.Order}, {Key: "_id", Value: s.Order}}
+}
+
+// keysetCursor is the position of the last document of a page
+type keysetCursor struct {
+	Field string             `bson:"f"`
+	Order int                `bson:"o"`
+	Value interface{}        `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+// encodeCursor returns the opaque cursor for the position after a document
+func encodeCursor(sort sortSpec, value interface{}, id primitive.ObjectID) (string, error) {
+	data, err := bson.Marshal(keysetCursor{Field: sort.Field, Order: sort.Order, Value: value, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor decodes a cursor and checks that it belongs to the sort
+func decodeCursor(cursor string, sort sortSpec) (*keysetCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var position keysetCursor
+	if err := bson.Unmarshal(data, &position); err != nil {
+		return nil, errInvalidCursor
+	}
+	if position.Field != sort.Field || position.Order != sort.Order || position.ID.IsZero() {
+		return nil, errInvalidCursor
+	}
+	return &position, nil
+}
+
+// after returns the filter for the documents after the cursor position
+func (c *keysetCursor) after() bson.M {
+	op := "$gt"
+	if c.Order < 0 {
+		op = "$lt"
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{c.Field: bson.M{op: c.Value}},
+		bson.M{c.Field: c.Value, "_id": bson.M{op: c.ID}},
+	}}
+}
+
+// andFilter adds a condition to a filter that may already use $and
+func andFilter(filter bson.M, condition bson.M) {
+	if clauses, ok := filter["$and"].(bson.A); ok {
+		filter["$and"] = append(clauses, condition)
+		return
+	}
+	filter["$and"] = bson.A{condition}
+}
+
+// sortOrder returns 1 for asc, -1 for desc and the default otherwise
+func sortOrder(order string, defaultOrder int) int {
+	switch order {
+	case domain.SortAscending:
+		return 1
+	case domain.SortDescending:
+		return -1
+	}
+	return defaultOrder
+}
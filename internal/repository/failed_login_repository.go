@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// failedLoginRepository implements domain.FailedLoginRepository
+type failedLoginRepository struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewFailedLoginRepository creates a new failed login repository
+func NewFailedLoginRepository(db *database.MongoDB) domain.FailedLoginRepository {
+	return &failedLoginRepository{
+		db:         db,
+		collection: db.GetCollection("failed_logins"),
+	}
+}
+
+// Create records a failed login attempt
+func (r *failedLoginRepository) Create(ctx context.Context, attempt *domain.FailedLogin) error {
+	attempt.ID = primitive.NewObjectID()
+	attempt.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, attempt)
+	return err
+}
+
+// CountByIP returns the number of failed logins from an IP address since the
+// given time. Logins refused while throttled or locked are left out, as they
+// never checked a password and would otherwise keep the IP blocked forever.
+func (r *failedLoginRepository) CountByIP(ctx context.Context, ipAddress string, since time.Time) (int64, error) {
+	filter := bson.M{
+		"ip_address": ipAddress,
+		"created_at": bson.M{"$gte": since},
+		"reason": bson.M{"$nin": bson.A{
+			domain.LoginFailureIPThrottled,
+			domain.LoginFailureAccountLocked,
+		}},
+	}
+	return r.collection.CountDocuments(ctx, filter)
+}
+
+// List retrieves failed logins with filtering and pagination, newest first
+func (r *failedLoginRepository) List(ctx context.Context, filter domain.FailedLoginFilter) ([]*domain.FailedLogin, error) {
+	opts := options.Find()
+
+	// Pagination
+	if filter.Page > 0 && filter.Limit > 0 {
+		skip := (filter.Page - 1) * filter.Limit
+		opts.SetSkip(int64(skip))
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, buildFailedLoginFilter(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []*domain.FailedLogin
+	for cursor.Next(ctx) {
+		var attempt domain.FailedLogin
+		if err := cursor.Decode(&attempt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, &attempt)
+	}
+
+	return attempts, cursor.Err()
+}
+
+// Count returns the total count of failed logins matching the filter
+func (r *failedLoginRepository) Count(ctx context.Context, filter domain.FailedLoginFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, buildFailedLoginFilter(filter))
+}
+
+// buildFailedLoginFilter builds the MongoDB filter for a failed login filter
+func buildFailedLoginFilter(filter domain.FailedLoginFilter) bson.M {
+	mongoFilter := bson.M{}
+
+	if filter.Email != "" {
+		mongoFilter["email"] = filter.Email
+	}
+	if filter.IPAddress != "" {
+		mongoFilter["ip_address"] = filter.IPAddress
+	}
+	if filter.UserID != nil {
+		mongoFilter["user_id"] = *filter.UserID
+	}
+
+	return mongoFilter
+}
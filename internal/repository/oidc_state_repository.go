@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// oidcStateRepository implements domain.OIDCStateRepository
+type oidcStateRepository struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewOIDCStateRepository creates a new OpenID Connect state repository
+func NewOIDCStateRepository(db *database.MongoDB) domain.OIDCStateRepository {
+	return &oidcStateRepository{
+		db:         db,
+		collection: db.GetCollection("oidc_states"),
+	}
+}
+
+// Create stores a pending login
+func (r *oidcStateRepository) Create(ctx context.Context, state *domain.OIDCState) error {
+	state.ID = primitive.NewObjectID()
+	state.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, state)
+	return err
+}
+
+// Consume removes a pending login and returns it, so every state can only be
+// used once. It returns nil when the state is unknown or expired.
+func (r *oidcStateRepository) Consume(ctx context.Context, stateHash string) (*domain.OIDCState, error) {
+	filter := bson.M{"state_hash": stateHash, "expires_at": bson.M{"$gt": time.Now()}}
+
+	var state domain.OIDCState
+	err := r.collection.FindOneAndDelete(ctx, filter).Decode(&state)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &state, nil
+}
@@ -3,7 +3,11 @@ package repository
 import (
 	"agricultural-equipment-store/internal/domain"
 	"agricultural-equipment-store/internal/infrastructure/database"
+	"agricultural-equipment-store/internal/utils"
 	"context"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,6 +16,9 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// priceFacetBoundaries are the lower bounds of the price range facets
+var priceFacetBoundaries = []float64{0, 100, 250, 500, 1000, 2500, 5000, 10000, 25000}
+
 // productRepository implements domain.ProductRepository
 type productRepository struct {
 	db         *database.MongoDB
@@ -29,6 +36,7 @@ func NewProductRepository(db *database.MongoDB) domain.ProductRepository {
 // Create creates a new product
 func (r *productRepository) Create(ctx context.Context, product *domain.Product) error {
 	product.ID = primitive.NewObjectID()
+	product.Version = 1
 	product.CreatedAt = time.Now()
 	product.UpdatedAt = time.Now()
 
@@ -39,7 +47,20 @@ func (r *productRepository) Create(ctx context.Context, product *domain.Product)
 // GetByID retrieves a product by ID
 func (r *productRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*domain.Product, error) {
 	var product domain.Product
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&product)
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": nil}).Decode(&product)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+// GetDeletedByID retrieves a product in the trash by ID
+func (r *productRepository) GetDeletedByID(ctx context.Context, id primitive.ObjectID) (*domain.Product, error) {
+	var product domain.Product
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}}).Decode(&product)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -53,50 +74,140 @@ func (r *productRepository) GetByID(ctx context.Context, id primitive.ObjectID)
 func (r *productRepository) Update(ctx context.Context, product *domain.Product) error {
 	product.UpdatedAt = time.Now()
 
-	filter := bson.M{"_id": product.ID}
-	update := bson.M{"$set": product}
+	// The sold count is only changed by IncrementSoldCount, so sales made
+	// while the product was being edited are not lost
+	data, err := bson.Marshal(product)
+	if err != nil {
+		return err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	delete(fields, "sold_count")
+	delete(fields, "version")
 
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	// Only the version the product was read at is updated, so concurrent
+	// changes are not overwritten
+	filter := bson.M{"_id": product.ID, "version": versionFilter(product.Version)}
+	update := bson.M{"$set": fields, "$inc": bson.M{"version": 1}}
+
+	if err := r.updateVersioned(ctx, filter, update); err != nil {
+		return err
+	}
+	product.Version++
+	return nil
 }
 
-// Delete deletes a product
-func (r *productRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+// IncrementSoldCount adds sold units to the sold count of a product
+func (r *productRepository) IncrementSoldCount(ctx context.Context, id primitive.ObjectID, quantity int) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"sold_count": quantity}})
 	return err
 }
 
-// List retrieves a list of products with filtering and pagination
-func (r *productRepository) List(ctx context.Context, filter domain.ProductFilter) ([]*domain.Product, error) {
-	// Build MongoDB filter
-	mongoFilter := bson.M{}
+// RenameCategory moves every product, including those in the trash, from one
+// category name to another and returns the number of products moved. Their
+// versions change, so edits based on the old category are refused.
+func (r *productRepository) RenameCategory(ctx context.Context, from, to string) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx, bson.M{"category": from}, bson.M{
+		"$set": bson.M{"category": to, "updated_at": time.Now()},
+		"$inc": bson.M{"version": 1},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
 
-	if filter.Category != "" {
-		mongoFilter["category"] = filter.Category
+// BackfillSoldCounts sets the sold count of products created before it was
+// tracked from their recorded sales
+func (r *productRepository) BackfillSoldCounts(ctx context.Context) (int64, error) {
+	missing := bson.M{"sold_count": bson.M{"$exists": false}}
+	count, err := r.collection.CountDocuments(ctx, missing)
+	if err != nil || count == 0 {
+		return 0, err
 	}
-	if filter.Brand != "" {
-		mongoFilter["brand"] = filter.Brand
+
+	cursor, err := r.db.GetCollection("sales").Aggregate(ctx, []bson.M{
+		{"$group": bson.M{"_id": "$product_id", "sold": bson.M{"$sum": "$quantity"}}},
+	})
+	if err != nil {
+		return 0, err
 	}
-	if filter.MinPrice > 0 || filter.MaxPrice > 0 {
-		priceFilter := bson.M{}
-		if filter.MinPrice > 0 {
-			priceFilter["$gte"] = filter.MinPrice
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var sold struct {
+			ProductID primitive.ObjectID `bson:"_id"`
+			Sold      int                `bson:"sold"`
+		}
+		if err := cursor.Decode(&sold); err != nil {
+			return 0, err
 		}
-		if filter.MaxPrice > 0 {
-			priceFilter["$lte"] = filter.MaxPrice
+		filter := bson.M{"_id": sold.ProductID, "sold_count": bson.M{"$exists": false}}
+		if _, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"sold_count": sold.Sold}}); err != nil {
+			return 0, err
 		}
-		mongoFilter["price"] = priceFilter
 	}
-	if filter.IsActive != nil {
-		mongoFilter["is_active"] = *filter.IsActive
+	if err := cursor.Err(); err != nil {
+		return 0, err
 	}
-	if filter.Search != "" {
-		// Search primarily in name only for more precise results
-		mongoFilter["name"] = bson.M{"$regex": filter.Search, "$options": "i"}
+
+	// Products that were never sold
+	if _, err := r.collection.UpdateMany(ctx, missing, bson.M{"$set": bson.M{"sold_count": 0}}); err != nil {
+		return 0, err
 	}
+	return count, nil
+}
+
+// Delete permanently deletes a product
+func (r *productRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// SoftDelete moves a product to the trash
+func (r *productRepository) SoftDelete(ctx context.Context, id, deletedBy primitive.ObjectID) error {
+	now := time.Now()
+	filter := bson.M{"_id": id, "deleted_at": nil}
+	update := bson.M{
+		"$set": bson.M{
+			"deleted_at": now,
+			"deleted_by": deletedBy,
+			"updated_at": now,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// Restore moves a product out of the trash
+func (r *productRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	filter := bson.M{"_id": id}
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": "", "deleted_by": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$inc":   bson.M{"version": 1},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// ListDeletedBefore retrieves the products moved to the trash before a time
+func (r *productRepository) ListDeletedBefore(ctx context.Context, before time.Time) ([]*domain.Product, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: 1}})
+	return r.find(ctx, bson.M{"deleted_at": bson.M{"$lt": before}}, opts)
+}
+
+// List retrieves a list of products with filtering and pagination
+func (r *productRepository) List(ctx context.Context, filter domain.ProductFilter) ([]*domain.Product, error) {
+	mongoFilter := buildProductFilter(filter)
 
 	// Build options
-	opts := options.Find()
+	opts := listOptions(filter, mongoFilter)
 
 	// Pagination
 	if filter.Page > 0 && filter.Limit > 0 {
@@ -105,15 +216,297 @@ func (r *productRepository) List(ctx context.Context, filter domain.ProductFilte
 		opts.SetLimit(int64(filter.Limit))
 	}
 
-	// Sort by creation date (newest first)
-	opts.SetSort(bson.D{{"created_at", -1}})
+	return r.find(ctx, mongoFilter, opts)
+}
+
+// ForEach calls fn with every product matching the filter in list order,
+// ignoring pagination, without loading all of them into memory
+func (r *productRepository) ForEach(ctx context.Context, filter domain.ProductFilter, fn func(*domain.Product) error) error {
+	mongoFilter := buildProductFilter(filter)
+
+	cursor, err := r.collection.Find(ctx, mongoFilter, listOptions(filter, mongoFilter))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		product, err := decodeScoredProduct(cursor)
+		if err != nil {
+			return err
+		}
+		if err := fn(product); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// listOptions returns the sort and projection of a product list
+func listOptions(filter domain.ProductFilter, mongoFilter bson.M) *options.FindOptions {
+	opts := options.Find()
+
+	_, isTextSearch := mongoFilter["$text"]
+	if isTextSearch {
+		opts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}})
+	}
+	if isTextSearch && (filter.Sort == "" || filter.Sort == "relevance") {
+		// Most relevant first
+		opts.SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}, {Key: "created_at", Value: -1}})
+	} else {
+		opts.SetSort(productSort(filter).document())
+	}
+
+	return opts
+}
+
+// ListWithCursor retrieves a page of products after filter.Cursor and the
+// cursor of the next page, which is empty on the last page
+func (r *productRepository) ListWithCursor(ctx context.Context, filter domain.ProductFilter) ([]*domain.Product, string, error) {
+	mongoFilter := buildProductFilter(filter)
+	sortBy := productSort(filter)
+
+	if filter.Cursor != nil && *filter.Cursor != "" {
+		position, err := decodeCursor(*filter.Cursor, sortBy)
+		if err != nil {
+			return nil, "", err
+		}
+		andFilter(mongoFilter, position.after())
+	}
 
+	// One extra product tells whether there is a next page
+	opts := options.Find().SetSort(sortBy.document()).SetLimit(int64(filter.Limit) + 1)
+	if _, ok := mongoFilter["$text"]; ok {
+		opts.SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}})
+	}
+
+	products, err := r.find(ctx, mongoFilter, opts)
+	if err != nil || len(products) <= filter.Limit {
+		return products, "", err
+	}
+
+	products = products[:filter.Limit]
+	last := products[len(products)-1]
+	nextCursor, err := encodeCursor(sortBy, productSortValue(last, sortBy.Field), last.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return products, nextCursor, nil
+}
+
+// find runs a product query, keeping the text score of search results
+func (r *productRepository) find(ctx context.Context, mongoFilter bson.M, opts *options.FindOptions) ([]*domain.Product, error) {
 	cursor, err := r.collection.Find(ctx, mongoFilter, opts)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
+	var products []*domain.Product
+	for cursor.Next(ctx) {
+		product, err := decodeScoredProduct(cursor)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+
+	return products, cursor.Err()
+}
+
+// decodeScoredProduct decodes the current product of a cursor with its text
+// search score
+func decodeScoredProduct(cursor *mongo.Cursor) (*domain.Product, error) {
+	var result struct {
+		domain.Product `bson:",inline"`
+		Score          float64 `bson:"score"`
+	}
+	if err := cursor.Decode(&result); err != nil {
+		return nil, err
+	}
+	product := result.Product
+	product.Score = result.Score
+	return &product, nil
+}
+
+// productSortFields maps the sort options of a product filter to fields
+var productSortFields = map[string]string{
+	"price":      "price",
+	"name":       "name",
+	"stock":      "stock",
+	"created_at": "created_at",
+	"popularity": "sold_count",
+	"deleted_at": "deleted_at",
+}
+
+// productSort returns the sort of a product filter, newest first by default
+func productSort(filter domain.ProductFilter) sortSpec {
+	field, ok := productSortFields[filter.Sort]
+	if !ok {
+		field = "created_at"
+	}
+
+	defaultOrder := -1
+	if field == "name" {
+		defaultOrder = 1
+	}
+	return sortSpec{Field: field, Order: sortOrder(filter.Order, defaultOrder)}
+}
+
+// productSortValue returns the value of the sort field of a product
+func productSortValue(product *domain.Product, field string) interface{} {
+	switch field {
+	case "price":
+		return product.Price
+	case "name":
+		return product.Name
+	case "stock":
+		return product.Stock
+	case "sold_count":
+		return product.SoldCount
+	case "deleted_at":
+		if product.DeletedAt != nil {
+			return *product.DeletedAt
+		}
+		return nil
+	default:
+		return product.CreatedAt
+	}
+}
+
+// Count returns the total count of products matching the filter
+func (r *productRepository) Count(ctx context.Context, filter domain.ProductFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, buildProductFilter(filter))
+}
+
+// GetFacets counts the products matching the filter per category, brand,
+// price range and availability
+func (r *productRepository) GetFacets(ctx context.Context, filter domain.ProductFilter) (*domain.ProductFacets, error) {
+	countByValue := func(field string) bson.A {
+		return bson.A{
+			bson.M{"$match": bson.M{field: bson.M{"$nin": bson.A{"", nil}}}},
+			bson.M{"$group": bson.M{"_id": "$" + field, "count": bson.M{"$sum": 1}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		}
+	}
+
+	pipeline := []bson.M{
+		{
+			"$match": buildProductFilter(filter),
+		},
+		{
+			"$facet": bson.M{
+				"categories": countByValue("category"),
+				"brands":     countByValue("brand"),
+				"price_ranges": bson.A{
+					bson.M{"$bucket": bson.M{
+						"groupBy":    "$price",
+						"boundaries": priceFacetBoundaries,
+						"default":    "above",
+						"output":     bson.M{"count": bson.M{"$sum": 1}},
+					}},
+				},
+				"availability": bson.A{
+					bson.M{"$group": bson.M{
+						"_id":          nil,
+						"in_stock":     bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$stock", 0}}, 1, 0}}},
+						"out_of_stock": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$stock", 0}}, 0, 1}}},
+					}},
+				},
+			},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		domain.ProductFacets `bson:",inline"`
+		PriceRanges          []struct {
+			LowerBound interface{} `bson:"_id"`
+			Count      int64       `bson:"count"`
+		} `bson:"price_ranges"`
+		Availability []domain.AvailabilityFacet `bson:"availability"`
+	}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return nil, err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	facets := result.ProductFacets
+	if facets.Categories == nil {
+		facets.Categories = []domain.FacetCount{}
+	}
+	if facets.Brands == nil {
+		facets.Brands = []domain.FacetCount{}
+	}
+
+	facets.PriceRanges = []domain.PriceRangeFacet{}
+	for _, bucket := range result.PriceRanges {
+		priceRange := domain.PriceRangeFacet{Count: bucket.Count}
+		if lowerBound, ok := bucket.LowerBound.(float64); ok {
+			priceRange.Min = lowerBound
+			for i, boundary := range priceFacetBoundaries[:len(priceFacetBoundaries)-1] {
+				if boundary == lowerBound {
+					upperBound := priceFacetBoundaries[i+1]
+					priceRange.Max = &upperBound
+				}
+			}
+		} else {
+			// Prices above the last boundary
+			priceRange.Min = priceFacetBoundaries[len(priceFacetBoundaries)-1]
+		}
+		facets.PriceRanges = append(facets.PriceRanges, priceRange)
+	}
+
+	if len(result.Availability) > 0 {
+		facets.Availability = result.Availability[0]
+	}
+
+	return &facets, nil
+}
+
+// CountBySpec counts the products with a value for a spec attribute, limited
+// to the given values unless they are empty
+func (r *productRepository) CountBySpec(ctx context.Context, key string, values []interface{}) (int64, error) {
+	spec := bson.M{"key": key}
+	if len(values) > 0 {
+		spec["value"] = bson.M{"$in": values}
+	}
+	return r.collection.CountDocuments(ctx, bson.M{"specs": bson.M{"$elemMatch": spec}})
+}
+
+// ListByCodes retrieves the products whose SKU, barcodes or variant SKUs and
+// barcodes include any of the codes, including products in the trash
+func (r *productRepository) ListByCodes(ctx context.Context, codes []string) ([]*domain.Product, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	in := bson.M{"$in": codes}
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"sku": in},
+			bson.M{"barcodes.code": in},
+			bson.M{"variants.sku": in},
+			bson.M{"variants.barcodes.code": in},
+		},
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
 	var products []*domain.Product
 	for cursor.Next(ctx) {
 		var product domain.Product
@@ -126,10 +519,42 @@ func (r *productRepository) List(ctx context.Context, filter domain.ProductFilte
 	return products, cursor.Err()
 }
 
-// Count returns the total count of products matching the filter
-func (r *productRepository) Count(ctx context.Context, filter domain.ProductFilter) (int64, error) {
-	// Build MongoDB filter (same as List method)
-	mongoFilter := bson.M{}
+// ListByIDs retrieves the products with the given IDs, excluding products in
+// the trash
+func (r *productRepository) ListByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*domain.Product, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}, "deleted_at": nil}, options.Find())
+}
+
+// ListByName retrieves the products with exactly the given name, excluding
+// products in the trash
+func (r *productRepository) ListByName(ctx context.Context, name string) ([]*domain.Product, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"name": name, "deleted_at": nil})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var products []*domain.Product
+	for cursor.Next(ctx) {
+		var product domain.Product
+		if err := cursor.Decode(&product); err != nil {
+			return nil, err
+		}
+		products = append(products, &product)
+	}
+
+	return products, cursor.Err()
+}
+
+// buildProductFilter builds the MongoDB filter shared by List and Count
+func buildProductFilter(filter domain.ProductFilter) bson.M {
+	mongoFilter := bson.M{"deleted_at": nil}
+	if filter.Deleted {
+		mongoFilter["deleted_at"] = bson.M{"$ne": nil}
+	}
 
 	if filter.Category != "" {
 		mongoFilter["category"] = filter.Category
@@ -137,48 +562,134 @@ func (r *productRepository) Count(ctx context.Context, filter domain.ProductFilt
 	if filter.Brand != "" {
 		mongoFilter["brand"] = filter.Brand
 	}
-	if filter.MinPrice > 0 || filter.MaxPrice > 0 {
-		priceFilter := bson.M{}
-		if filter.MinPrice > 0 {
-			priceFilter["$gte"] = filter.MinPrice
+	priceFilter := bson.M{}
+	if filter.MinPrice > 0 {
+		priceFilter["$gte"] = filter.MinPrice
+	}
+	if filter.MaxPrice > 0 {
+		priceFilter["$lte"] = filter.MaxPrice
+	}
+	if len(filter.VariantAttributes) > 0 {
+		// A single variant has to match all attributes and the price range
+		variantFilter := bson.M{"is_active": true}
+		for name, value := range filter.VariantAttributes {
+			variantFilter["attributes."+name] = value
 		}
-		if filter.MaxPrice > 0 {
-			priceFilter["$lte"] = filter.MaxPrice
+		if len(priceFilter) > 0 {
+			variantFilter["price"] = priceFilter
+		}
+		mongoFilter["variants"] = bson.M{"$elemMatch": variantFilter}
+	} else if len(priceFilter) > 0 {
+		// The product price is the lowest variant price, so variants priced
+		// within the range are checked as well
+		mongoFilter["$or"] = bson.A{
+			bson.M{"price": priceFilter},
+			bson.M{"variants": bson.M{"$elemMatch": bson.M{"is_active": true, "price": priceFilter}}},
 		}
-		mongoFilter["price"] = priceFilter
 	}
 	if filter.IsActive != nil {
 		mongoFilter["is_active"] = *filter.IsActive
 	}
-	if filter.Search != "" {
-		// Search primarily in name only for more precise results
-		mongoFilter["name"] = bson.M{"$regex": filter.Search, "$options": "i"}
+	if terms := utils.SearchTerms(filter.Search); len(terms) > 0 {
+		if filter.PartialSearch {
+			// Every term has to appear in one of the fields
+			var clauses bson.A
+			for _, term := range terms {
+				pattern := bson.M{"$regex": regexp.QuoteMeta(term), "$options": "i"}
+				clauses = append(clauses, bson.M{"$or": bson.A{
+					bson.M{"name": pattern},
+					bson.M{"brand": pattern},
+					bson.M{"description": pattern},
+					bson.M{"sku": pattern},
+				}})
+			}
+			mongoFilter["$and"] = clauses
+		} else {
+			mongoFilter["$text"] = bson.M{"$search": strings.Join(terms, " ")}
+		}
+	}
+	for _, spec := range filter.Specs {
+		// The values and the range are separate conditions on the same spec
+		if len(spec.Values) > 0 {
+			andFilter(mongoFilter, bson.M{"specs": bson.M{"$elemMatch": bson.M{
+				"key":   spec.Key,
+				"value": bson.M{"$in": spec.Values},
+			}}})
+		}
+		valueRange := bson.M{}
+		if spec.Min != nil {
+			valueRange["$gte"] = *spec.Min
+		}
+		if spec.Max != nil {
+			valueRange["$lte"] = *spec.Max
+		}
+		if len(valueRange) > 0 {
+			andFilter(mongoFilter, bson.M{"specs": bson.M{"$elemMatch": bson.M{
+				"key":   spec.Key,
+				"value": valueRange,
+			}}})
+		}
 	}
 
-	return r.collection.CountDocuments(ctx, mongoFilter)
+	return mongoFilter
 }
 
-// UpdateStock updates the stock quantity for a product
-func (r *productRepository) UpdateStock(ctx context.Context, id primitive.ObjectID, stock int) error {
-	filter := bson.M{"_id": id}
+// UpdateStock updates the stock quantity for a product at a version
+func (r *productRepository) UpdateStock(ctx context.Context, id primitive.ObjectID, version int64, stock int) error {
+	filter := bson.M{"_id": id, "version": versionFilter(version)}
 	update := bson.M{
 		"$set": bson.M{
 			"stock":      stock,
 			"updated_at": time.Now(),
 		},
+		"$inc": bson.M{"version": 1},
 	}
 
-	_, err := r.collection.UpdateOne(ctx, filter, update)
-	return err
+	return r.updateVersioned(ctx, filter, update)
 }
 
-// GetLowStockProducts retrieves products with stock below the threshold
-func (r *productRepository) GetLowStockProducts(ctx context.Context, threshold int) ([]*domain.LowStockProduct, error) {
-	filter := bson.M{
-		"stock":     bson.M{"$lt": threshold},
-		"is_active": true,
+// UpdateVariantStock updates the stock quantity for a variant of a product at
+// a version and recalculates the product stock as the total of its variants
+func (r *productRepository) UpdateVariantStock(ctx context.Context, id primitive.ObjectID, version int64, variantID string, stock int) error {
+	filter := bson.M{"_id": id, "version": versionFilter(version), "variants.id": variantID}
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			"variants": bson.M{"$map": bson.M{
+				"input": "$variants",
+				"as":    "variant",
+				"in": bson.M{"$cond": bson.A{
+					bson.M{"$eq": bson.A{"$$variant.id", variantID}},
+					bson.M{"$mergeObjects": bson.A{"$$variant", bson.M{"stock": stock}}},
+					"$$variant",
+				}},
+			}},
+			"updated_at": time.Now(),
+			"version":    nextVersion(),
+		}},
+		bson.M{"$set": bson.M{"stock": bson.M{"$sum": "$variants.stock"}}},
 	}
 
+	return r.updateVersioned(ctx, filter, update)
+}
+
+// updateVersioned updates the product matching a filter on its version and
+// returns errVersionMismatch when it changed since that version was read
+func (r *productRepository) updateVersioned(ctx context.Context, filter bson.M, update interface{}) error {
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errVersionMismatch
+	}
+	return nil
+}
+
+// GetLowStockProducts retrieves products with stock below the threshold. For
+// products with variants each variant below the threshold is listed instead.
+func (r *productRepository) GetLowStockProducts(ctx context.Context, threshold int) ([]*domain.LowStockProduct, error) {
+	filter := lowStockFilter(threshold)
+
 	opts := options.Find()
 	opts.SetSort(bson.D{{"stock", 1}}) // Sort by stock ascending (lowest first)
 
@@ -195,33 +706,100 @@ func (r *productRepository) GetLowStockProducts(ctx context.Context, threshold i
 			return nil, err
 		}
 
-		lowStockProduct := &domain.LowStockProduct{
-			ID:       product.ID,
-			Name:     product.Name,
-			Stock:    product.Stock,
-			Category: product.Category,
-			Price:    product.Price,
+		if len(product.Variants) == 0 {
+			lowStockProducts = append(lowStockProducts, &domain.LowStockProduct{
+				ID:       product.ID,
+				SKU:      product.SKU,
+				Name:     product.Name,
+				Stock:    product.Stock,
+				Category: product.Category,
+				Price:    product.Price,
+			})
+			continue
 		}
-		lowStockProducts = append(lowStockProducts, lowStockProduct)
+
+		for _, variant := range product.Variants {
+			if !variant.IsActive || variant.Stock >= threshold {
+				continue
+			}
+			lowStockProducts = append(lowStockProducts, &domain.LowStockProduct{
+				ID:          product.ID,
+				SKU:         product.SKU,
+				Name:        product.Name,
+				VariantID:   variant.ID,
+				VariantSKU:  variant.SKU,
+				VariantName: variant.Name,
+				Stock:       variant.Stock,
+				Category:    product.Category,
+				Price:       variant.Price,
+			})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
 	}
 
-	return lowStockProducts, cursor.Err()
+	// Variants are listed after their product was sorted by total stock
+	sort.SliceStable(lowStockProducts, func(i, j int) bool {
+		return lowStockProducts[i].Stock < lowStockProducts[j].Stock
+	})
+
+	return lowStockProducts, nil
+}
+
+// lowStockFilter matches active products, or active variants of products,
+// with stock below the threshold
+func lowStockFilter(threshold int) bson.M {
+	return bson.M{
+		"is_active":  true,
+		"deleted_at": nil,
+		"$or": bson.A{
+			bson.M{"stock": bson.M{"$lt": threshold}, "variants.0": bson.M{"$exists": false}},
+			bson.M{"variants": bson.M{"$elemMatch": bson.M{"is_active": true, "stock": bson.M{"$lt": threshold}}}},
+		},
+	}
 }
 
 // GetStockSummary retrieves stock summary data
 func (r *productRepository) GetStockSummary(ctx context.Context) (*domain.StockSummary, error) {
 	pipeline := []bson.M{
 		{
-			"$match": bson.M{"is_active": true},
+			"$match": bson.M{"is_active": true, "deleted_at": nil},
+		},
+		{
+			// Variants are valued at their own price
+			"$addFields": bson.M{
+				"stock_value": bson.M{"$cond": bson.A{
+					bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$variants", bson.A{}}}}, 0}},
+					bson.M{"$sum": bson.M{"$map": bson.M{
+						"input": "$variants",
+						"as":    "variant",
+						"in":    bson.M{"$multiply": bson.A{"$$variant.stock", "$$variant.price"}},
+					}}},
+					bson.M{"$multiply": bson.A{"$stock", "$price"}},
+				}},
+			},
 		},
 		{
 			"$group": bson.M{
 				"_id":           "$category",
 				"total_stock":   bson.M{"$sum": "$stock"},
-				"total_value":   bson.M{"$sum": bson.M{"$multiply": []interface{}{"$stock", "$price"}}},
+				"total_value":   bson.M{"$sum": "$stock_value"},
 				"product_count": bson.M{"$sum": 1},
 			},
 		},
+		{
+			"$project": bson.M{
+				"_id":           0,
+				"category":      "$_id",
+				"total_stock":   1,
+				"total_value":   1,
+				"product_count": 1,
+			},
+		},
+		{
+			"$sort": bson.M{"category": 1},
+		},
 	}
 
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
@@ -245,10 +823,7 @@ func (r *productRepository) GetStockSummary(ctx context.Context) (*domain.StockS
 	}
 
 	// Get low stock products count
-	lowStockCount, err := r.collection.CountDocuments(ctx, bson.M{
-		"stock":     bson.M{"$lt": 10},
-		"is_active": true,
-	})
+	lowStockCount, err := r.collection.CountDocuments(ctx, lowStockFilter(10))
 	if err != nil {
 		return nil, err
 	}
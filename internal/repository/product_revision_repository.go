@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// productRevisionRepository implements domain.ProductRevisionRepository
+type productRevisionRepository struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewProductRevisionRepository creates a new product revision repository
+func NewProductRevisionRepository(db *database.MongoDB) domain.ProductRevisionRepository {
+	return &productRevisionRepository{
+		db:         db,
+		collection: db.GetCollection("product_revisions"),
+	}
+}
+
+// Create records a product revision
+func (r *productRevisionRepository) Create(ctx context.Context, revision *domain.ProductRevision) error {
+	revision.ID = primitive.NewObjectID()
+	revision.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, revision)
+	return err
+}
+
+// ListByProduct retrieves the revisions of a product, newest first
+func (r *productRevisionRepository) ListByProduct(ctx context.Context, productID primitive.ObjectID, page, limit int) ([]*domain.ProductRevision, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	return r.find(ctx, bson.M{"product_id": productID}, opts)
+}
+
+// CountByProduct returns the number of revisions of a product
+func (r *productRevisionRepository) CountByProduct(ctx context.Context, productID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"product_id": productID})
+}
+
+// ListByFields retrieves the revisions of a product that changed any of the
+// fields, oldest first
+func (r *productRevisionRepository) ListByFields(ctx context.Context, productID primitive.ObjectID, fields []string) ([]*domain.ProductRevision, error) {
+	filter := bson.M{
+		"product_id":    productID,
+		"changes.field": bson.M{"$in": fields},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	return r.find(ctx, filter, opts)
+}
+
+// find runs a revision query. Old and new values that are documents, such
+// as added variants, are decoded as maps so they serialize as JSON objects.
+func (r *productRevisionRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]*domain.ProductRevision, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []*domain.ProductRevision
+	for cursor.Next(ctx) {
+		decoder, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(cursor.Current))
+		if err != nil {
+			return nil, err
+		}
+		decoder.DefaultDocumentM()
+
+		var revision domain.ProductRevision
+		if err := decoder.Decode(&revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &revision)
+	}
+
+	return revisions, cursor.Err()
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// roleRepository implements domain.RoleRepository
+type roleRepository struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(db *database.MongoDB) domain.RoleRepository {
+	return &roleRepository{
+		db:         db,
+		collection: db.GetCollection("roles"),
+	}
+}
+
+// Create creates a new role
+func (r *roleRepository) Create(ctx context.Context, role *domain.Role) error {
+	role.ID = primitive.NewObjectID()
+	role.CreatedAt = time.Now()
+	role.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, role)
+	return err
+}
+
+// GetByName retrieves a role by name
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*domain.Role, error) {
+	var role domain.Role
+	err := r.collection.FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// List retrieves all roles
+func (r *roleRepository) List(ctx context.Context) ([]*domain.Role, error) {
+	opts := options.Find().SetSort(bson.M{"name": 1})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var roles []*domain.Role
+	for cursor.Next(ctx) {
+		var role domain.Role
+		if err := cursor.Decode(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, &role)
+	}
+
+	return roles, cursor.Err()
+}
+
+// Update updates a role
+func (r *roleRepository) Update(ctx context.Context, role *domain.Role) error {
+	role.UpdatedAt = time.Now()
+
+	filter := bson.M{"_id": role.ID}
+	update := bson.M{"$set": role}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// Delete deletes a role
+func (r *roleRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
@@ -51,23 +51,7 @@ func (r *saleRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*d
 
 // List retrieves a list of sales with filtering and pagination
 func (r *saleRepository) List(ctx context.Context, filter domain.SaleFilter) ([]*domain.Sale, error) {
-	// Build MongoDB filter
-	mongoFilter := bson.M{}
-
-	if !filter.ProductID.IsZero() {
-		mongoFilter["product_id"] = filter.ProductID
-	}
-
-	if !filter.FromDate.IsZero() && !filter.ToDate.IsZero() {
-		mongoFilter["date_sold"] = bson.M{
-			"$gte": filter.FromDate,
-			"$lte": filter.ToDate,
-		}
-	} else if !filter.FromDate.IsZero() {
-		mongoFilter["date_sold"] = bson.M{"$gte": filter.FromDate}
-	} else if !filter.ToDate.IsZero() {
-		mongoFilter["date_sold"] = bson.M{"$lte": filter.ToDate}
-	}
+	mongoFilter := buildSaleFilter(filter)
 
 	// Set up pagination
 	page := filter.Page
@@ -84,8 +68,54 @@ func (r *saleRepository) List(ctx context.Context, filter domain.SaleFilter) ([]
 	opts := options.Find()
 	opts.SetSkip(int64(skip))
 	opts.SetLimit(int64(limit))
-	opts.SetSort(bson.D{{"date_sold", -1}})
+	opts.SetSort(saleSort(filter).document())
+
+	return r.find(ctx, mongoFilter, opts)
+}
+
+// ListWithCursor retrieves a page of sales after filter.Cursor and the cursor
+// of the next page, which is empty on the last page
+func (r *saleRepository) ListWithCursor(ctx context.Context, filter domain.SaleFilter) ([]*domain.Sale, string, error) {
+	mongoFilter := buildSaleFilter(filter)
+	sortBy := saleSort(filter)
+
+	if filter.Cursor != nil && *filter.Cursor != "" {
+		position, err := decodeCursor(*filter.Cursor, sortBy)
+		if err != nil {
+			return nil, "", err
+		}
+		andFilter(mongoFilter, position.after())
+	}
 
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	// One extra sale tells whether there is a next page
+	opts := options.Find().SetSort(sortBy.document()).SetLimit(int64(limit) + 1)
+
+	sales, err := r.find(ctx, mongoFilter, opts)
+	if err != nil || len(sales) <= limit {
+		return sales, "", err
+	}
+
+	sales = sales[:limit]
+	last := sales[len(sales)-1]
+	nextCursor, err := encodeCursor(sortBy, saleSortValue(last, sortBy.Field), last.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return sales, nextCursor, nil
+}
+
+// Count counts sales with filtering
+func (r *saleRepository) Count(ctx context.Context, filter domain.SaleFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, buildSaleFilter(filter))
+}
+
+// find runs a sale query
+func (r *saleRepository) find(ctx context.Context, mongoFilter bson.M, opts *options.FindOptions) ([]*domain.Sale, error) {
 	cursor, err := r.collection.Find(ctx, mongoFilter, opts)
 	if err != nil {
 		return nil, err
@@ -104,8 +134,8 @@ func (r *saleRepository) List(ctx context.Context, filter domain.SaleFilter) ([]
 	return sales, cursor.Err()
 }
 
-// Count counts sales with filtering
-func (r *saleRepository) Count(ctx context.Context, filter domain.SaleFilter) (int64, error) {
+// buildSaleFilter builds the MongoDB filter shared by List and Count
+func buildSaleFilter(filter domain.SaleFilter) bson.M {
 	mongoFilter := bson.M{}
 
 	if !filter.ProductID.IsZero() {
@@ -123,7 +153,28 @@ func (r *saleRepository) Count(ctx context.Context, filter domain.SaleFilter) (i
 		mongoFilter["date_sold"] = bson.M{"$lte": filter.ToDate}
 	}
 
-	return r.collection.CountDocuments(ctx, mongoFilter)
+	return mongoFilter
+}
+
+// saleSort returns the sort of a sale filter, most recent first by default
+func saleSort(filter domain.SaleFilter) sortSpec {
+	field := filter.Sort
+	if field != "total" && field != "quantity" {
+		field = "date_sold"
+	}
+	return sortSpec{Field: field, Order: sortOrder(filter.Order, -1)}
+}
+
+// saleSortValue returns the value of the sort field of a sale
+func saleSortValue(sale *domain.Sale, field string) interface{} {
+	switch field {
+	case "total":
+		return sale.Total
+	case "quantity":
+		return sale.Quantity
+	default:
+		return sale.DateSold
+	}
 }
 
 // GetSalesSummary retrieves sales summary for a date range
@@ -192,6 +243,7 @@ func (r *saleRepository) GetSalesByProduct(ctx context.Context, fromDate, toDate
 		{
 			"$group": bson.M{
 				"_id":           "$product_id",
+				"product_name":  bson.M{"$last": "$product_name"},
 				"total_sold":    bson.M{"$sum": "$quantity"},
 				"total_revenue": bson.M{"$sum": "$total"},
 			},
@@ -205,12 +257,17 @@ func (r *saleRepository) GetSalesByProduct(ctx context.Context, fromDate, toDate
 			},
 		},
 		{
-			"$unwind": "$product",
+			// Sales of purged products are kept in the report
+			"$unwind": bson.M{"path": "$product", "preserveNullAndEmptyArrays": true},
 		},
 		{
 			"$project": bson.M{
-				"product_id":    "$_id",
-				"product_name":  "$product.name",
+				"product_id":   "$_id",
+				"product_name": bson.M{"$ifNull": bson.A{"$product.name", "$product_name"}},
+				"product_deleted": bson.M{"$or": bson.A{
+					bson.M{"$eq": bson.A{bson.M{"$type": "$product"}, "missing"}},
+					bson.M{"$ne": bson.A{bson.M{"$ifNull": bson.A{"$product.deleted_at", nil}}, nil}},
+				}},
 				"total_sold":    1,
 				"total_revenue": 1,
 			},
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// specAttributeRepository implements domain.SpecAttributeRepository
+type specAttributeRepository struct {
+	db         *database.MongoDB
+	collection *mongo.Collection
+}
+
+// NewSpecAttributeRepository creates a new spec attribute repository
+func NewSpecAttributeRepository(db *database.MongoDB) domain.SpecAttributeRepository {
+	return &specAttributeRepository{
+		db:         db,
+		collection: db.GetCollection("spec_attributes"),
+	}
+}
+
+// Create creates a new spec attribute
+func (r *specAttributeRepository) Create(ctx context.Context, attribute *domain.SpecAttribute) error {
+	attribute.ID = primitive.NewObjectID()
+	attribute.CreatedAt = time.Now()
+	attribute.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, attribute)
+	return err
+}
+
+// GetByID retrieves a spec attribute by ID
+func (r *specAttributeRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*domain.SpecAttribute, error) {
+	var attribute domain.SpecAttribute
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&attribute)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attribute, nil
+}
+
+// GetByKey retrieves a spec attribute by key
+func (r *specAttributeRepository) GetByKey(ctx context.Context, key string) (*domain.SpecAttribute, error) {
+	var attribute domain.SpecAttribute
+	err := r.collection.FindOne(ctx, bson.M{"key": key}).Decode(&attribute)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attribute, nil
+}
+
+// List retrieves all spec attributes ordered by key
+func (r *specAttributeRepository) List(ctx context.Context) ([]*domain.SpecAttribute, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "key", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var attributes []*domain.SpecAttribute
+	for cursor.Next(ctx) {
+		var attribute domain.SpecAttribute
+		if err := cursor.Decode(&attribute); err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, &attribute)
+	}
+
+	return attributes, cursor.Err()
+}
+
+// Update updates a spec attribute
+func (r *specAttributeRepository) Update(ctx context.Context, attribute *domain.SpecAttribute) error {
+	attribute.UpdatedAt = time.Now()
+
+	filter := bson.M{"_id": attribute.ID}
+	update := bson.M{"$set": attribute}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// Delete deletes a spec attribute
+func (r *specAttributeRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
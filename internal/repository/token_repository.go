@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/database"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// tokenRepository implements domain.TokenRepository
+type tokenRepository struct {
+	db                *database.MongoDB
+	refreshCollection *mongo.Collection
+	revokedCollection *mongo.Collection
+	userCollection    *mongo.Collection
+}
+
+// NewTokenRepository creates a new token repository
+func NewTokenRepository(db *database.MongoDB) domain.TokenRepository {
+	return &tokenRepository{
+		db:                db,
+		refreshCollection: db.GetCollection("refresh_tokens"),
+		revokedCollection: db.GetCollection("revoked_tokens"),
+		userCollection:    db.GetCollection("user_tokens"),
+	}
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, token *domain.RefreshToken) error {
+	// The ID may be reserved up front to link a rotated token to its replacement
+	if token.ID.IsZero() {
+		token.ID = primitive.NewObjectID()
+	}
+	token.CreatedAt = time.Now()
+
+	_, err := r.refreshCollection.InsertOne(ctx, token)
+	return err
+}
+
+// GetRefreshTokenByHash retrieves a refresh token by its hash
+func (r *tokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	err := r.refreshCollection.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeRefreshToken revokes a single refresh token. It reports false when the
+// token had already been revoked, which lets callers detect token reuse.
+func (r *tokenRepository) RevokeRefreshToken(ctx context.Context, id primitive.ObjectID, replacedBy *primitive.ObjectID) (bool, error) {
+	set := bson.M{"revoked_at": time.Now()}
+	if replacedBy != nil {
+		set["replaced_by"] = *replacedBy
+	}
+
+	filter := bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}}
+	result, err := r.refreshCollection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// RevokeRefreshTokenFamily revokes every token rotated from the same login
+func (r *tokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	filter := bson.M{"family_id": familyID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	_, err := r.refreshCollection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of a user
+func (r *tokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID primitive.ObjectID) error {
+	filter := bson.M{"user_id": userID, "revoked_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now()}}
+
+	_, err := r.refreshCollection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+// ListActiveRefreshTokens retrieves the unrevoked, unexpired refresh tokens of
+// a user, newest first. There is one per active session.
+func (r *tokenRepository) ListActiveRefreshTokens(ctx context.Context, userID primitive.ObjectID, limit int) ([]*domain.RefreshToken, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"revoked_at": bson.M{"$exists": false},
+		"expires_at": bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+
+	cursor, err := r.refreshCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*domain.RefreshToken
+	for cursor.Next(ctx) {
+		var token domain.RefreshToken
+		if err := cursor.Decode(&token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+
+	return tokens, cursor.Err()
+}
+
+// RevokeAccessToken adds an access token to the revocation list
+func (r *tokenRepository) RevokeAccessToken(ctx context.Context, token *domain.RevokedToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	_, err := r.revokedCollection.InsertOne(ctx, token)
+	if mongo.IsDuplicateKeyError(err) {
+		// Already revoked
+		return nil
+	}
+	return err
+}
+
+// IsAccessTokenRevoked checks whether an access token has been revoked
+func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	count, err := r.revokedCollection.CountDocuments(ctx, bson.M{"jti": jti})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateUserToken stores a new one-time user token
+func (r *tokenRepository) CreateUserToken(ctx context.Context, token *domain.UserToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	_, err := r.userCollection.InsertOne(ctx, token)
+	return err
+}
+
+// GetUserTokenByHash retrieves a one-time user token by its purpose and hash
+func (r *tokenRepository) GetUserTokenByHash(ctx context.Context, purpose, tokenHash string) (*domain.UserToken, error) {
+	var token domain.UserToken
+	err := r.userCollection.FindOne(ctx, bson.M{"purpose": purpose, "token_hash": tokenHash}).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUserTokenUsed marks a one-time user token as used. It reports false when
+// the token had already been used, so a token can only be redeemed once.
+func (r *tokenRepository) MarkUserTokenUsed(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	filter := bson.M{"_id": id, "used_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"used_at": time.Now()}}
+
+	result, err := r.userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// DeleteUserTokens deletes every one-time token of a user with the given purpose
+func (r *tokenRepository) DeleteUserTokens(ctx context.Context, userID primitive.ObjectID, purpose string) error {
+	_, err := r.userCollection.DeleteMany(ctx, bson.M{"user_id": userID, "purpose": purpose})
+	return err
+}
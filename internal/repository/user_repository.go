@@ -4,6 +4,7 @@ import (
 	"agricultural-equipment-store/internal/domain"
 	"agricultural-equipment-store/internal/infrastructure/database"
 	"context"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -62,12 +63,29 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*domain.
 	return &user, nil
 }
 
+// GetByOIDCSubject retrieves the user linked to an account at an OpenID Connect provider
+func (r *userRepository) GetByOIDCSubject(ctx context.Context, issuer, subject string) (*domain.User, error) {
+	var user domain.User
+	err := r.collection.FindOne(ctx, bson.M{"oidc_issuer": issuer, "oidc_subject": subject}).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 // Update updates a user
 func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
 	user.UpdatedAt = time.Now()
 
 	filter := bson.M{"_id": user.ID}
 	update := bson.M{"$set": user}
+	// Omitted when empty, so turning MFA off has to remove the stored time
+	if user.MFAEnabledAt == nil {
+		update["$unset"] = bson.M{"mfa_enabled_at": ""}
+	}
 
 	_, err := r.collection.UpdateOne(ctx, filter, update)
 	return err
@@ -79,16 +97,20 @@ func (r *userRepository) Delete(ctx context.Context, id primitive.ObjectID) erro
 	return err
 }
 
-// List retrieves a list of users with pagination
-func (r *userRepository) List(ctx context.Context, page, limit int) ([]*domain.User, error) {
-	skip := (page - 1) * limit
-
+// List retrieves a list of users with filtering and pagination
+func (r *userRepository) List(ctx context.Context, filter domain.UserFilter) ([]*domain.User, error) {
 	opts := options.Find()
-	opts.SetSkip(int64(skip))
-	opts.SetLimit(int64(limit))
-	opts.SetSort(bson.D{{"created_at", -1}})
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	// Pagination
+	if filter.Page > 0 && filter.Limit > 0 {
+		skip := (filter.Page - 1) * filter.Limit
+		opts.SetSkip(int64(skip))
+		opts.SetLimit(int64(filter.Limit))
+	}
+
+	opts.SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := r.collection.Find(ctx, buildUserFilter(filter), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -105,3 +127,150 @@ func (r *userRepository) List(ctx context.Context, page, limit int) ([]*domain.U
 
 	return users, cursor.Err()
 }
+
+// Count returns the total count of users matching the filter
+func (r *userRepository) Count(ctx context.Context, filter domain.UserFilter) (int64, error) {
+	return r.collection.CountDocuments(ctx, buildUserFilter(filter))
+}
+
+// IncrementFailedLogins records a failed login on the user and returns the
+// number of consecutive failures
+func (r *userRepository) IncrementFailedLogins(ctx context.Context, id primitive.ObjectID) (int, error) {
+	update := bson.M{
+		"$inc": bson.M{"failed_login_attempts": 1},
+		"$set": bson.M{"last_failed_login_at": time.Now()},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var user domain.User
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&user)
+	if err != nil {
+		return 0, err
+	}
+	return user.FailedLoginAttempts, nil
+}
+
+// SetLockedUntil refuses logins for the user until the given time
+func (r *userRepository) SetLockedUntil(ctx context.Context, id primitive.ObjectID, lockedUntil time.Time) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"locked_until": lockedUntil}})
+	return err
+}
+
+// ResetFailedLogins clears the failed login counter and any lockout of the user
+func (r *userRepository) ResetFailedLogins(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{
+		"$set":   bson.M{"failed_login_attempts": 0},
+		"$unset": bson.M{"last_failed_login_at": "", "locked_until": ""},
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// UseMFAStep records the time step of an accepted authenticator code. It
+// reports false when a code of the same or a later step was already used.
+func (r *userRepository) UseMFAStep(ctx context.Context, id primitive.ObjectID, step int64) (bool, error) {
+	filter := bson.M{
+		"_id": id,
+		"$or": []bson.M{
+			{"mfa_last_used_step": bson.M{"$exists": false}},
+			{"mfa_last_used_step": bson.M{"$lt": step}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"mfa_last_used_step": step}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// ConsumeRecoveryCode removes a recovery code from the user. It reports false
+// when the code does not exist or was already used.
+func (r *userRepository) ConsumeRecoveryCode(ctx context.Context, id primitive.ObjectID, codeHash string) (bool, error) {
+	filter := bson.M{"_id": id, "mfa_recovery_codes": codeHash}
+	update := bson.M{"$pull": bson.M{"mfa_recovery_codes": codeHash}}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// SetRecoveryCodes replaces the recovery code hashes of a user without
+// touching the other MFA fields, which codes are checked against concurrently
+func (r *userRepository) SetRecoveryCodes(ctx context.Context, id primitive.ObjectID, codeHashes []string) error {
+	update := bson.M{"$set": bson.M{"mfa_recovery_codes": codeHashes, "updated_at": time.Now()}}
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// ListWithPlaintextMFASecrets retrieves the users with an MFA secret or
+// pending secret that does not start with the prefix of encrypted secrets
+func (r *userRepository) ListWithPlaintextMFASecrets(ctx context.Context, encryptedPrefix string) ([]*domain.User, error) {
+	plaintext := bson.M{
+		"$nin": bson.A{"", nil},
+		"$not": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(encryptedPrefix)},
+	}
+	filter := bson.M{"$or": bson.A{
+		bson.M{"mfa_secret": plaintext},
+		bson.M{"mfa_pending_secret": plaintext},
+	}}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*domain.User
+	for cursor.Next(ctx) {
+		var user domain.User
+		if err := cursor.Decode(&user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+
+	return users, cursor.Err()
+}
+
+// MarkLegacyEmailsVerified treats users created before email verification
+// existed as verified. New users store a null email_verified_at, so only
+// documents without the field are updated.
+func (r *userRepository) MarkLegacyEmailsVerified(ctx context.Context) (int64, error) {
+	filter := bson.M{"email_verified_at": bson.M{"$exists": false}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"email_verified_at": "$created_at"}}},
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// buildUserFilter builds the MongoDB filter for a user filter
+func buildUserFilter(filter domain.UserFilter) bson.M {
+	mongoFilter := bson.M{}
+
+	if filter.Role != "" {
+		mongoFilter["role"] = filter.Role
+	}
+	if filter.IsActive != nil {
+		mongoFilter["is_active"] = *filter.IsActive
+	}
+	if filter.Search != "" {
+		// Escape the input so it is matched literally
+		pattern := regexp.QuoteMeta(filter.Search)
+		mongoFilter["$or"] = []bson.M{
+			{"name": bson.M{"$regex": pattern, "$options": "i"}},
+			{"email": bson.M{"$regex": pattern, "$options": "i"}},
+		}
+	}
+
+	return mongoFilter
+}
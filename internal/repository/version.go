@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// errVersionMismatch is returned when a document changed since the version
+// an update was based on was read
+var errVersionMismatch = errors.New("version mismatch")
+
+// versionFilter matches documents at a version. Documents saved before
+// versioning have no version field and are at version 0.
+func versionFilter(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// nextVersion is an aggregation expression for the version after the
+// current one, for updates with a pipeline where $inc is not available
+func nextVersion() bson.M {
+	return bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}}
+}
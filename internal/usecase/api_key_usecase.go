@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// apiKeyPrefix marks API keys so they are easy to recognize in configuration and logs
+	apiKeyPrefix = "ak_"
+	// apiKeyLastUsedInterval limits how often the last used timestamp is written
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// APIKeyUseCase handles API key business logic
+type APIKeyUseCase struct {
+	apiKeyRepo domain.APIKeyRepository
+	userRepo   domain.UserRepository
+	roleRepo   domain.RoleRepository
+}
+
+// NewAPIKeyUseCase creates a new API key use case
+func NewAPIKeyUseCase(apiKeyRepo domain.APIKeyRepository, userRepo domain.UserRepository, roleRepo domain.RoleRepository) *APIKeyUseCase {
+	return &APIKeyUseCase{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		roleRepo:   roleRepo,
+	}
+}
+
+// CreateAPIKey creates an API key acting as the given user. The scopes must be
+// granted by the role of that user. The key is only returned here.
+func (u *APIKeyUseCase) CreateAPIKey(ctx context.Context, actorID primitive.ObjectID, req domain.CreateAPIKeyRequest) (*domain.CreateAPIKeyResponse, error) {
+	userID, err := primitive.ObjectIDFromHex(req.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.IsActive {
+		return nil, errors.New("user account is inactive")
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expiry must be in the future")
+	}
+
+	granted, err := u.rolePermissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range req.Scopes {
+		if !domain.IsValidPermission(scope) {
+			return nil, fmt.Errorf("unknown permission %q", scope)
+		}
+		if !domain.HasPermission(granted, scope) {
+			return nil, fmt.Errorf("scope %q is not granted to the user", scope)
+		}
+	}
+
+	secret, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	rawKey := apiKeyPrefix + secret
+
+	key := &domain.APIKey{
+		Name:      req.Name,
+		Prefix:    rawKey[:len(apiKeyPrefix)+8],
+		KeyHash:   hashToken(rawKey),
+		UserID:    user.ID,
+		Scopes:    req.Scopes,
+		CreatedBy: actorID,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if err := u.apiKeyRepo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &domain.CreateAPIKeyResponse{
+		Key:    rawKey,
+		APIKey: *key,
+	}, nil
+}
+
+// ListAPIKeys retrieves API keys with filtering and pagination
+func (u *APIKeyUseCase) ListAPIKeys(ctx context.Context, filter domain.APIKeyFilter) ([]*domain.APIKey, int64, error) {
+	// Set default pagination values
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+
+	keys, err := u.apiKeyRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := u.apiKeyRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return keys, count, nil
+}
+
+// GetAPIKey retrieves an API key by ID
+func (u *APIKeyUseCase) GetAPIKey(ctx context.Context, id primitive.ObjectID) (*domain.APIKey, error) {
+	key, err := u.apiKeyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errors.New("API key not found")
+	}
+	return key, nil
+}
+
+// RevokeAPIKey revokes an API key, it stops working immediately
+func (u *APIKeyUseCase) RevokeAPIKey(ctx context.Context, id primitive.ObjectID) error {
+	if _, err := u.GetAPIKey(ctx, id); err != nil {
+		return err
+	}
+	return u.apiKeyRepo.Revoke(ctx, id)
+}
+
+// Authenticate validates an API key and returns the identity it acts as. The
+// permissions are the scopes of the key that the user's role still grants.
+func (u *APIKeyUseCase) Authenticate(ctx context.Context, rawKey, ipAddress string) (*domain.APIKeyIdentity, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, errors.New("invalid API key")
+	}
+
+	key, err := u.apiKeyRepo.GetByHash(ctx, hashToken(rawKey))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil || key.RevokedAt != nil {
+		return nil, errors.New("invalid API key")
+	}
+	if key.ExpiresAt != nil && time.Now().After(*key.ExpiresAt) {
+		return nil, errors.New("API key expired")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, key.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return nil, errors.New("invalid API key")
+	}
+
+	granted, err := u.rolePermissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	permissions := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		if domain.HasPermission(granted, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	if err := u.apiKeyRepo.TouchLastUsed(ctx, key.ID, ipAddress, apiKeyLastUsedInterval); err != nil {
+		return nil, err
+	}
+
+	return &domain.APIKeyIdentity{
+		Key:         key,
+		User:        user,
+		Permissions: permissions,
+	}, nil
+}
+
+// rolePermissions returns the permissions granted to a role
+func (u *APIKeyUseCase) rolePermissions(ctx context.Context, roleName string) ([]string, error) {
+	role, err := u.roleRepo.GetByName(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return []string{}, nil
+	}
+	return role.Permissions, nil
+}
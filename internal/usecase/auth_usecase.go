@@ -2,30 +2,137 @@ package usecase
 
 import (
 	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/logger"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// TokenSigner signs and verifies the JWTs issued by the auth use case
+type TokenSigner interface {
+	Sign(claims jwt.MapClaims) (string, error)
+	Parse(token string, options ...jwt.ParserOption) (jwt.MapClaims, error)
+	JWKS() domain.JSONWebKeySet
+}
+
+// AuthConfig holds token settings for the auth use case
+type AuthConfig struct {
+	Signer          TokenSigner
+	Issuer          string // Set as the iss claim and required on every token when not empty
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	PasswordResetTTL time.Duration
+	PasswordResetURL string // Frontend page that receives the reset token as the "token" query parameter
+
+	EmailVerificationTTL time.Duration
+	EmailChangeURL       string // Frontend page that receives the email change token as the "token" query parameter
+	EmailVerificationURL string // Frontend page that receives the verification token as the "token" query parameter
+
+	RequireVerifiedEmailForLogin    bool
+	RequireVerifiedEmailForPurchase bool
+
+	MaxFailedLogins   int           // Consecutive failures before the account is locked
+	LoginBackoffBase  time.Duration // Delay after the first failure, doubled after every further failure
+	LockoutDuration   time.Duration
+	IPMaxFailedLogins int // Failures from one IP address within IPLoginWindow before it is throttled
+	IPLoginWindow     time.Duration
+
+	MFAIssuer        string   // Account issuer shown in authenticator apps
+	MFARequiredRoles []string // Roles that must use two-factor authentication
+	MFASecretKey     []byte   // 32-byte key the TOTP secrets are encrypted with in the database
+}
+
+// failedLoginResetAfter is how long after the last failure the counter of a user starts over
+const failedLoginResetAfter = 24 * time.Hour
+
+// LoginThrottledError is returned when a login is refused because of too
+// many failed attempts
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool // The account is locked, rather than the client being throttled
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return "account is temporarily locked due to too many failed login attempts"
+	}
+	return "too many failed login attempts, try again later"
+}
+
 // AuthUseCase handles authentication related business logic
 type AuthUseCase struct {
 	userRepo  domain.UserRepository
-	jwtSecret string
+	roleRepo  domain.RoleRepository
+	tokenRepo domain.TokenRepository
+
+	failedLoginRepo domain.FailedLoginRepository
+	apiKeyRepo      domain.APIKeyRepository
+	mailer          domain.Mailer
+	logger          logger.Logger
+	config          AuthConfig
 }
 
 // NewAuthUseCase creates a new auth use case
-func NewAuthUseCase(userRepo domain.UserRepository, jwtSecret string) *AuthUseCase {
+func NewAuthUseCase(userRepo domain.UserRepository, roleRepo domain.RoleRepository, tokenRepo domain.TokenRepository, failedLoginRepo domain.FailedLoginRepository, apiKeyRepo domain.APIKeyRepository, mailer domain.Mailer, logger logger.Logger, config AuthConfig) *AuthUseCase {
+	if config.AccessTokenTTL <= 0 {
+		config.AccessTokenTTL = 15 * time.Minute
+	}
+	if config.RefreshTokenTTL <= 0 {
+		config.RefreshTokenTTL = 7 * 24 * time.Hour
+	}
+	if config.PasswordResetTTL <= 0 {
+		config.PasswordResetTTL = time.Hour
+	}
+	if config.EmailVerificationTTL <= 0 {
+		config.EmailVerificationTTL = 24 * time.Hour
+	}
+	if config.MaxFailedLogins <= 0 {
+		config.MaxFailedLogins = 5
+	}
+	if config.LoginBackoffBase <= 0 {
+		config.LoginBackoffBase = time.Second
+	}
+	if config.LockoutDuration <= 0 {
+		config.LockoutDuration = 15 * time.Minute
+	}
+	if config.IPMaxFailedLogins <= 0 {
+		config.IPMaxFailedLogins = 20
+	}
+	if config.IPLoginWindow <= 0 {
+		config.IPLoginWindow = 15 * time.Minute
+	}
+	if config.MFAIssuer == "" {
+		config.MFAIssuer = "Agricultural Equipment Store"
+	}
+
 	return &AuthUseCase{
 		userRepo:  userRepo,
-		jwtSecret: jwtSecret,
+		roleRepo:  roleRepo,
+		tokenRepo: tokenRepo,
+
+		failedLoginRepo: failedLoginRepo,
+		apiKeyRepo:      apiKeyRepo,
+		mailer:          mailer,
+		logger:          logger,
+		config:          config,
 	}
 }
 
-// Register registers a new user
+// Register registers a new user and emails them a verification link.
+// Self-registered users always get the least-privileged role; staff accounts
+// are created by an admin.
 func (u *AuthUseCase) Register(ctx context.Context, req domain.CreateUserRequest) (*domain.User, error) {
 	// Check if user already exists
 	existingUser, err := u.userRepo.GetByEmail(ctx, req.Email)
@@ -37,23 +144,17 @@ func (u *AuthUseCase) Register(ctx context.Context, req domain.CreateUserRequest
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(req.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	// Set default role if not provided
-	role := req.Role
-	if role == "" {
-		role = "user"
-	}
-
 	// Create user
 	user := &domain.User{
 		Email:    req.Email,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
 		Name:     req.Name,
-		Role:     role,
+		Role:     domain.RoleUser,
 		IsActive: true,
 	}
 
@@ -62,41 +163,414 @@ func (u *AuthUseCase) Register(ctx context.Context, req domain.CreateUserRequest
 		return nil, err
 	}
 
+	// The account exists either way; the user can ask for the link again
+	if err := u.sendVerificationEmail(ctx, user); err != nil {
+		u.logger.Error("Failed to send verification email to user %s: %v", user.ID.Hex(), err)
+	}
+
 	return user, nil
 }
 
-// Login authenticates a user and returns JWT token
-func (u *AuthUseCase) Login(ctx context.Context, req domain.LoginRequest) (*domain.LoginResponse, error) {
+// Login authenticates a user and returns an access token and a refresh token.
+// Users with two-factor authentication get an MFA challenge instead, which is
+// completed with VerifyMFA. Failed attempts are throttled per account and per
+// IP address.
+func (u *AuthUseCase) Login(ctx context.Context, req domain.LoginRequest) (*domain.LoginResponse, *domain.MFAChallengeResponse, error) {
+	// Throttle clients that fail too often, whichever accounts they try
+	if req.IPAddress != "" {
+		since := time.Now().Add(-u.config.IPLoginWindow)
+		failures, err := u.failedLoginRepo.CountByIP(ctx, req.IPAddress, since)
+		if err != nil {
+			return nil, nil, err
+		}
+		if failures >= int64(u.config.IPMaxFailedLogins) {
+			if err := u.recordFailedLogin(ctx, req, nil, domain.LoginFailureIPThrottled); err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, &LoginThrottledError{RetryAfter: u.config.IPLoginWindow}
+		}
+	}
+
 	// Get user by email
 	user, err := u.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if user == nil {
-		return nil, errors.New("invalid email or password")
+		if err := u.recordFailedLogin(ctx, req, nil, domain.LoginFailureUnknownEmail); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("invalid email or password")
+	}
+
+	// Refuse logins while the account is locked or backing off
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		if err := u.recordFailedLogin(ctx, req, &user.ID, domain.LoginFailureAccountLocked); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, &LoginThrottledError{
+			RetryAfter: time.Until(*user.LockedUntil),
+			Locked:     user.FailedLoginAttempts >= u.config.MaxFailedLogins,
+		}
 	}
 
 	// Check if user is active
 	if !user.IsActive {
-		return nil, errors.New("user account is inactive")
+		return nil, nil, errors.New("user account is inactive")
 	}
 
 	// Verify password
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
-		return nil, errors.New("invalid email or password")
+		if err := u.handleFailedLogin(ctx, req, user, domain.LoginFailureInvalidPassword); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, errors.New("invalid email or password")
+	}
+
+	// Only checked once the password is known to be right, so the error does
+	// not reveal anything about the account to someone guessing
+	if u.config.RequireVerifiedEmailForLogin && user.EmailVerifiedAt == nil {
+		return nil, nil, errors.New("email address is not verified")
+	}
+
+	// The password is only the first step for users with two-factor
+	// authentication. Failures are only reset once the code is accepted, so
+	// logging in again does not clear the backoff of wrong codes.
+	if user.MFAEnabled {
+		challenge, err := u.issueMFAChallenge(user)
+		return nil, challenge, err
+	}
+
+	if err := u.clearFailedLogins(ctx, user); err != nil {
+		return nil, nil, err
 	}
 
-	// Generate JWT token
-	token, err := u.generateJWT(user)
+	response, err := u.startSession(ctx, user, req.IPAddress, req.UserAgent)
+	return response, nil, err
+}
+
+// clearFailedLogins resets the failed login counter and backoff of a user
+// who has fully authenticated
+func (u *AuthUseCase) clearFailedLogins(ctx context.Context, user *domain.User) error {
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	if err := u.userRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+		return err
+	}
+	user.FailedLoginAttempts = 0
+	user.LastFailedLoginAt = nil
+	user.LockedUntil = nil
+	return nil
+}
+
+// startSession starts a new session for an authenticated user
+func (u *AuthUseCase) startSession(ctx context.Context, user *domain.User, ipAddress, userAgent string) (*domain.LoginResponse, error) {
+	session := &domain.RefreshToken{
+		UserID:           user.ID,
+		FamilyID:         uuid.New().String(),
+		IPAddress:        ipAddress,
+		UserAgent:        userAgent,
+		SessionStartedAt: time.Now(),
+	}
+
+	return u.issueTokens(ctx, user, session)
+}
+
+// handleFailedLogin counts a wrong password or MFA code on the account and
+// makes the next attempt wait. The delay doubles with every failure until the
+// account is locked for LockoutDuration.
+func (u *AuthUseCase) handleFailedLogin(ctx context.Context, req domain.LoginRequest, user *domain.User, reason string) error {
+	if err := u.recordFailedLogin(ctx, req, &user.ID, reason); err != nil {
+		return err
+	}
+
+	// Old failures do not count against the user
+	if user.LastFailedLoginAt != nil && time.Since(*user.LastFailedLoginAt) > failedLoginResetAfter {
+		if err := u.userRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+
+	failures, err := u.userRepo.IncrementFailedLogins(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	delay := u.config.LockoutDuration
+	if failures < u.config.MaxFailedLogins {
+		delay = u.config.LoginBackoffBase << uint(failures-1)
+		if delay > u.config.LockoutDuration {
+			delay = u.config.LockoutDuration
+		}
+	}
+
+	return u.userRepo.SetLockedUntil(ctx, user.ID, time.Now().Add(delay))
+}
+
+// recordFailedLogin adds a failed login attempt to the audit trail
+func (u *AuthUseCase) recordFailedLogin(ctx context.Context, req domain.LoginRequest, userID *primitive.ObjectID, reason string) error {
+	return u.failedLoginRepo.Create(ctx, &domain.FailedLogin{
+		Email:     req.Email,
+		UserID:    userID,
+		IPAddress: req.IPAddress,
+		UserAgent: req.UserAgent,
+		Reason:    reason,
+	})
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. The presented
+// refresh token is rotated; presenting an already rotated token revokes the
+// whole session because it means the token has been copied.
+func (u *AuthUseCase) RefreshToken(ctx context.Context, req domain.RefreshTokenRequest) (*domain.LoginResponse, error) {
+	stored, err := u.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(req.RefreshToken))
 	if err != nil {
 		return nil, err
 	}
+	if stored == nil {
+		return nil, errors.New("invalid refresh token")
+	}
 
-	return &domain.LoginResponse{
-		Token: token,
-		User:  *user,
-	}, nil
+	if stored.RevokedAt != nil {
+		if err := u.tokenRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, errors.New("refresh token expired")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		if err := u.tokenRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("user account is inactive")
+	}
+
+	// Revoke the presented token before issuing its replacement so that two
+	// concurrent refreshes with the same token cannot both succeed
+	replacementID := primitive.NewObjectID()
+	revoked, err := u.tokenRepo.RevokeRefreshToken(ctx, stored.ID, &replacementID)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		if err := u.tokenRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("invalid refresh token")
+	}
+
+	session := &domain.RefreshToken{
+		ID:               replacementID,
+		UserID:           user.ID,
+		FamilyID:         stored.FamilyID,
+		IPAddress:        req.IPAddress,
+		UserAgent:        req.UserAgent,
+		SessionStartedAt: stored.SessionStartedAt,
+	}
+
+	return u.issueTokens(ctx, user, session)
+}
+
+// Logout revokes the access token used for the request and ends the session
+// of the given refresh token, or every session of the user when requested
+func (u *AuthUseCase) Logout(ctx context.Context, userID primitive.ObjectID, tokenID string, tokenExpiresAt time.Time, req domain.LogoutRequest) error {
+	if tokenID != "" {
+		err := u.tokenRepo.RevokeAccessToken(ctx, &domain.RevokedToken{
+			JTI:       tokenID,
+			UserID:    userID,
+			ExpiresAt: tokenExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if req.AllSessions {
+		return u.tokenRepo.RevokeUserRefreshTokens(ctx, userID)
+	}
+
+	if req.RefreshToken != "" {
+		stored, err := u.tokenRepo.GetRefreshTokenByHash(ctx, hashToken(req.RefreshToken))
+		if err != nil {
+			return err
+		}
+		// Silently ignore tokens that do not belong to the caller
+		if stored != nil && stored.UserID == userID {
+			return u.tokenRepo.RevokeRefreshTokenFamily(ctx, stored.FamilyID)
+		}
+	}
+
+	return nil
+}
+
+// ForgotPassword emails a one-time password reset link to the user. Unknown
+// and inactive accounts are ignored so the endpoint does not reveal which
+// emails are registered.
+func (u *AuthUseCase) ForgotPassword(ctx context.Context, req domain.ForgotPasswordRequest) error {
+	user, err := u.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.IsActive {
+		return nil
+	}
+
+	// Failing only for registered emails would reveal which emails are registered
+	if err := u.sendPasswordReset(ctx, user); err != nil {
+		u.logger.Error("Failed to send password reset email to user %s: %v", user.ID.Hex(), err)
+	}
+	return nil
+}
+
+// sendPasswordReset creates a password reset token and emails its link
+func (u *AuthUseCase) sendPasswordReset(ctx context.Context, user *domain.User) error {
+	// Only the most recent reset link is valid
+	if err := u.tokenRepo.DeleteUserTokens(ctx, user.ID, domain.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(u.config.PasswordResetTTL)
+	err = u.tokenRepo.CreateUserToken(ctx, &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   domain.TokenPurposePasswordReset,
+		TokenHash: hashToken(token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		"Hello %s,\n\nWe received a request to reset your password. Use the link below to choose a new one:\n\n%s\n\nThe link expires at %s. If you did not request a password reset you can ignore this email.\n",
+		user.Name, tokenLink(u.config.PasswordResetURL, token), expiresAt.Format(time.RFC1123),
+	)
+
+	return u.mailer.Send(ctx, domain.EmailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body:    body,
+	})
+}
+
+// ResetPassword sets a new password using a password reset token. Every
+// session of the user is ended.
+func (u *AuthUseCase) ResetPassword(ctx context.Context, req domain.ResetPasswordRequest) error {
+	stored, err := u.tokenRepo.GetUserTokenByHash(ctx, domain.TokenPurposePasswordReset, hashToken(req.Token))
+	if err != nil {
+		return err
+	}
+	if stored == nil || stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return errors.New("invalid or expired reset token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.IsActive {
+		return errors.New("invalid or expired reset token")
+	}
+
+	// Redeem the token first so it cannot be used twice concurrently
+	used, err := u.tokenRepo.MarkUserTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("invalid or expired reset token")
+	}
+
+	// The reset link was delivered to the address, which proves it exists
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := u.setPassword(ctx, user, req.NewPassword); err != nil {
+		return err
+	}
+
+	return u.tokenRepo.DeleteUserTokens(ctx, user.ID, domain.TokenPurposePasswordReset)
+}
+
+// ChangePassword changes the password of a logged in user after checking the
+// current one. Every session of the user is ended.
+func (u *AuthUseCase) ChangePassword(ctx context.Context, userID primitive.ObjectID, req domain.ChangePasswordRequest) error {
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return errors.New("current password is incorrect")
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return errors.New("new password must be different from the current password")
+	}
+
+	return u.setPassword(ctx, user, req.NewPassword)
+}
+
+// setPassword stores a new password for the user and revokes their refresh tokens
+func (u *AuthUseCase) setPassword(ctx context.Context, user *domain.User, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	// Proving ownership of the account lifts any lockout
+	if err := u.userRepo.ResetFailedLogins(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return u.tokenRepo.RevokeUserRefreshTokens(ctx, user.ID)
+}
+
+// tokenLink builds the link to a frontend page sent in emails with a one-time token
+func tokenLink(pageURL, token string) string {
+	if pageURL == "" {
+		return token
+	}
+	link, err := url.Parse(pageURL)
+	if err != nil {
+		return pageURL + "?token=" + url.QueryEscape(token)
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
+
+// IsTokenRevoked reports whether the access token with the given ID was revoked
+func (u *AuthUseCase) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if tokenID == "" {
+		return false, nil
+	}
+	return u.tokenRepo.IsAccessTokenRevoked(ctx, tokenID)
 }
 
 // GetUserByID retrieves a user by ID
@@ -104,36 +578,141 @@ func (u *AuthUseCase) GetUserByID(ctx context.Context, id primitive.ObjectID) (*
 	return u.userRepo.GetByID(ctx, id)
 }
 
-// generateJWT generates a JWT token for the user
-func (u *AuthUseCase) generateJWT(user *domain.User) (string, error) {
+// GetPermissions returns the permissions granted to a role
+func (u *AuthUseCase) GetPermissions(ctx context.Context, roleName string) ([]string, error) {
+	role, err := u.roleRepo.GetByName(ctx, roleName)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		// Users with an unknown role get no permissions
+		return []string{}, nil
+	}
+	return role.Permissions, nil
+}
+
+// issueTokens generates an access token and stores a new refresh token for the session
+func (u *AuthUseCase) issueTokens(ctx context.Context, user *domain.User, session *domain.RefreshToken) (*domain.LoginResponse, error) {
+	permissions, err := u.GetPermissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	// Until a user whose role requires MFA enrolls, the token grants no permissions
+	mfaSetupRequired := !user.MFAEnabled && u.isMFARequired(user.Role)
+	if mfaSetupRequired {
+		permissions = []string{}
+	}
+
+	accessToken, accessExpiresAt, err := u.generateJWT(user, permissions, mfaSetupRequired)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	session.TokenHash = hashToken(refreshToken)
+	session.ExpiresAt = time.Now().Add(u.config.RefreshTokenTTL)
+	if err := u.tokenRepo.CreateRefreshToken(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResponse{
+		Token:            accessToken,
+		ExpiresAt:        accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: session.ExpiresAt,
+		User:             *user,
+		MFASetupRequired: mfaSetupRequired,
+	}, nil
+}
+
+// generateJWT generates a JWT access token for the user with the permissions of their role
+func (u *AuthUseCase) generateJWT(user *domain.User, permissions []string, mfaSetupRequired bool) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(u.config.AccessTokenTTL)
+
 	claims := jwt.MapClaims{
-		"user_id": user.ID.Hex(),
-		"email":   user.Email,
-		"role":    user.Role,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
+		"jti":            uuid.New().String(),
+		"typ":            "access",
+		"user_id":        user.ID.Hex(),
+		"email":          user.Email,
+		"role":           user.Role,
+		"permissions":    permissions,
+		"email_verified": user.EmailVerifiedAt != nil,
+		"exp":            expiresAt.Unix(),
+		"iat":            now.Unix(),
+	}
+	if mfaSetupRequired {
+		claims["mfa_setup_required"] = true
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(u.jwtSecret))
+	signed, err := u.signClaims(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
 }
 
 // ValidateToken validates a JWT token and returns user claims
 func (u *AuthUseCase) ValidateToken(tokenString string) (*jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(u.jwtSecret), nil
-	})
-
+	claims, err := u.parseClaims(tokenString)
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return &claims, nil
+	// Only access tokens may be used to authenticate requests
+	if typ, ok := claims["typ"]; ok && typ != "access" {
+		return nil, errors.New("invalid token type")
+	}
+	return &claims, nil
+}
+
+// GetJWKS returns the public keys that verify the issued tokens
+func (u *AuthUseCase) GetJWKS() domain.JSONWebKeySet {
+	return u.config.Signer.JWKS()
+}
+
+// signClaims signs a set of claims with the active signing key
+func (u *AuthUseCase) signClaims(claims jwt.MapClaims) (string, error) {
+	if u.config.Issuer != "" {
+		claims["iss"] = u.config.Issuer
+	}
+	return u.config.Signer.Sign(claims)
+}
+
+// parseClaims verifies the signature, issuer and expiry of a token and returns its claims
+func (u *AuthUseCase) parseClaims(tokenString string) (jwt.MapClaims, error) {
+	var options []jwt.ParserOption
+	if u.config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(u.config.Issuer))
+	}
+	return u.config.Signer.Parse(tokenString, options...)
+}
+
+// hashPassword hashes a password with bcrypt
+func hashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
 	}
+	return string(hashed), nil
+}
+
+// generateRandomToken generates a URL-safe random token of n bytes
+func generateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	return nil, errors.New("invalid token")
+// hashToken returns the hex encoded SHA-256 hash of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
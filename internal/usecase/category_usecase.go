@@ -11,12 +11,14 @@ import (
 // CategoryUseCase handles category business logic
 type CategoryUseCase struct {
 	categoryRepo domain.CategoryRepository
+	productRepo  domain.ProductRepository
 }
 
 // NewCategoryUseCase creates a new category use case
-func NewCategoryUseCase(categoryRepo domain.CategoryRepository) *CategoryUseCase {
+func NewCategoryUseCase(categoryRepo domain.CategoryRepository, productRepo domain.ProductRepository) *CategoryUseCase {
 	return &CategoryUseCase{
 		categoryRepo: categoryRepo,
+		productRepo:  productRepo,
 	}
 }
 
@@ -66,6 +68,40 @@ func (u *CategoryUseCase) GetCategoryByID(ctx context.Context, id string) (*doma
 	return category, nil
 }
 
+// UpdateCategory renames a category at the version the client last read.
+// Products refer to their category by name and are moved along with it.
+func (u *CategoryUseCase) UpdateCategory(ctx context.Context, id string, version int64, req domain.UpdateCategoryRequest) (*domain.Category, error) {
+	category, err := u.GetCategoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if category.Version != version {
+		return nil, errors.New("version mismatch")
+	}
+
+	existing, err := u.categoryRepo.GetByName(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.ID != category.ID {
+		return nil, errors.New("category already exists")
+	}
+
+	oldName := category.Name
+	category.Name = req.Name
+	if err := u.categoryRepo.Update(ctx, category); err != nil {
+		return nil, err
+	}
+
+	if oldName != category.Name {
+		if _, err := u.productRepo.RenameCategory(ctx, oldName, category.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return category, nil
+}
+
 // DeleteCategory deletes a category
 func (u *CategoryUseCase) DeleteCategory(ctx context.Context, id string) error {
 	objID, err := parseObjectID(id)
@@ -0,0 +1,106 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// VerifyEmail marks the email of a user as verified using the token sent to it
+func (u *AuthUseCase) VerifyEmail(ctx context.Context, req domain.VerifyEmailRequest) (*domain.User, error) {
+	stored, err := u.tokenRepo.GetUserTokenByHash(ctx, domain.TokenPurposeEmailVerification, hashToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	used, err := u.tokenRepo.MarkUserTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, errors.New("invalid or expired verification token")
+	}
+
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+		if err := u.userRepo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.tokenRepo.DeleteUserTokens(ctx, user.ID, domain.TokenPurposeEmailVerification); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ResendVerificationEmail sends a new verification link. Unknown, inactive and
+// already verified accounts are ignored so the endpoint does not reveal which
+// emails are registered.
+func (u *AuthUseCase) ResendVerificationEmail(ctx context.Context, req domain.ResendVerificationRequest) error {
+	user, err := u.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.IsActive || user.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	return u.sendVerificationEmail(ctx, user)
+}
+
+// RequiresVerifiedEmailForPurchase reports whether users must verify their
+// email before they can record purchases
+func (u *AuthUseCase) RequiresVerifiedEmailForPurchase() bool {
+	return u.config.RequireVerifiedEmailForPurchase
+}
+
+// sendVerificationEmail emails a one-time verification link to a user
+func (u *AuthUseCase) sendVerificationEmail(ctx context.Context, user *domain.User) error {
+	// Only the most recent link is valid
+	if err := u.tokenRepo.DeleteUserTokens(ctx, user.ID, domain.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(u.config.EmailVerificationTTL)
+	err = u.tokenRepo.CreateUserToken(ctx, &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   domain.TokenPurposeEmailVerification,
+		TokenHash: hashToken(token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		"Hello %s,\n\nPlease confirm your email address by following the link below:\n\n%s\n\nThe link expires at %s. If you did not create an account you can ignore this email.\n",
+		user.Name, tokenLink(u.config.EmailVerificationURL, token), expiresAt.Format(time.RFC1123),
+	)
+
+	return u.mailer.Send(ctx, domain.EmailMessage{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    body,
+	})
+}
@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/logger"
 	"context"
 	"errors"
 	"time"
@@ -9,20 +10,28 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// maxStockAttempts is how often a sale reads the product and takes its stock
+// before giving up on concurrent changes to the product
+const maxStockAttempts = 3
+
 // InventoryUseCase handles inventory related business logic
 type InventoryUseCase struct {
-	productRepo domain.ProductRepository
+	productRepo  domain.ProductRepository
+	revisionRepo domain.ProductRevisionRepository
+	logger       logger.Logger
 }
 
 // NewInventoryUseCase creates a new inventory use case
-func NewInventoryUseCase(productRepo domain.ProductRepository) *InventoryUseCase {
+func NewInventoryUseCase(productRepo domain.ProductRepository, revisionRepo domain.ProductRevisionRepository, logger logger.Logger) *InventoryUseCase {
 	return &InventoryUseCase{
-		productRepo: productRepo,
+		productRepo:  productRepo,
+		revisionRepo: revisionRepo,
+		logger:       logger,
 	}
 }
 
-// UpdateStock updates the stock for a product
-func (u *InventoryUseCase) UpdateStock(ctx context.Context, id primitive.ObjectID, req domain.StockUpdateRequest) error {
+// UpdateStock updates the stock for a product at the version the client last read
+func (u *InventoryUseCase) UpdateStock(ctx context.Context, id primitive.ObjectID, version int64, req domain.StockUpdateRequest) error {
 	// Check if product exists
 	product, err := u.productRepo.GetByID(ctx, id)
 	if err != nil {
@@ -31,9 +40,26 @@ func (u *InventoryUseCase) UpdateStock(ctx context.Context, id primitive.ObjectI
 	if product == nil {
 		return errors.New("product not found")
 	}
+	if product.Version != version {
+		return errors.New("version mismatch")
+	}
+
+	variant, err := resolveVariant(product, req.VariantID)
+	if err != nil {
+		return err
+	}
+	if variant != nil {
+		err = u.productRepo.UpdateVariantStock(ctx, id, version, variant.ID, req.Stock)
+	} else {
+		err = u.productRepo.UpdateStock(ctx, id, version, req.Stock)
+	}
+	if err != nil {
+		return err
+	}
 
-	// Update stock
-	return u.productRepo.UpdateStock(ctx, id, req.Stock)
+	changes := stockChanges(product, variant, req.Stock)
+	recordRevision(ctx, u.revisionRepo, u.logger, id, domain.RevisionActionStock, changes)
+	return nil
 }
 
 // GetLowStockProducts retrieves products with low stock
@@ -52,66 +78,163 @@ func (u *InventoryUseCase) GetStockSummary(ctx context.Context) (*domain.StockSu
 
 // SaleUseCase handles sales related business logic
 type SaleUseCase struct {
-	saleRepo    domain.SaleRepository
-	productRepo domain.ProductRepository
+	saleRepo     domain.SaleRepository
+	productRepo  domain.ProductRepository
+	revisionRepo domain.ProductRevisionRepository
+	logger       logger.Logger
 }
 
 // NewSaleUseCase creates a new sale use case
-func NewSaleUseCase(saleRepo domain.SaleRepository, productRepo domain.ProductRepository) *SaleUseCase {
+func NewSaleUseCase(saleRepo domain.SaleRepository, productRepo domain.ProductRepository, revisionRepo domain.ProductRevisionRepository, logger logger.Logger) *SaleUseCase {
 	return &SaleUseCase{
-		saleRepo:    saleRepo,
-		productRepo: productRepo,
+		saleRepo:     saleRepo,
+		productRepo:  productRepo,
+		revisionRepo: revisionRepo,
+		logger:       logger,
 	}
 }
 
-// CreateSale creates a new sale and updates product stock
+// CreateSale creates a new sale and updates product stock. Without a price
+// the sale is made at the price of the product, or of the variant sold.
 func (u *SaleUseCase) CreateSale(ctx context.Context, req domain.CreateSaleRequest) (*domain.Sale, error) {
-	// Get product to verify it exists and has enough stock
-	product, err := u.productRepo.GetByID(ctx, req.ProductID)
-	if err != nil {
-		return nil, err
-	}
-	if product == nil {
-		return nil, errors.New("product not found")
+	var product *domain.Product
+	var variant *domain.ProductVariant
+	var newStock int
+	for attempt := 1; ; attempt++ {
+		var err error
+		product, variant, newStock, err = u.takeStock(ctx, req)
+		if err == nil {
+			break
+		}
+		// Another change to the product was saved since it was read, the
+		// stock is checked again at its new version
+		if err.Error() != "version mismatch" || attempt == maxStockAttempts {
+			return nil, err
+		}
 	}
 
-	// Check if there's enough stock
-	if product.Stock < req.Quantity {
-		return nil, errors.New("insufficient stock")
+	price := req.Price
+	if price == 0 {
+		price = product.Price
+		if variant != nil {
+			price = variant.Price
+		}
 	}
 
 	// Calculate total
-	total := req.Price * float64(req.Quantity)
+	total := price * float64(req.Quantity)
 
 	// Create sale
 	sale := &domain.Sale{
-		ProductID: req.ProductID,
-		Quantity:  req.Quantity,
-		Price:     req.Price,
-		Total:     total,
-		DateSold:  time.Now(),
+		ProductID:   req.ProductID,
+		ProductName: product.Name,
+		Quantity:    req.Quantity,
+		Price:       price,
+		Total:       total,
+		DateSold:    time.Now(),
+	}
+	if variant != nil {
+		sale.VariantID = variant.ID
+		sale.VariantSKU = variant.SKU
 	}
 
-	err = u.saleRepo.Create(ctx, sale)
+	err := u.saleRepo.Create(ctx, sale)
 	if err != nil {
 		return nil, err
 	}
 
-	// Update product stock
-	newStock := product.Stock - req.Quantity
-	err = u.productRepo.UpdateStock(ctx, req.ProductID, newStock)
-	if err != nil {
+	changes := stockChanges(product, variant, newStock)
+	recordRevision(ctx, u.revisionRepo, u.logger, req.ProductID, domain.RevisionActionSale, changes)
+
+	// Popularity sorting counts the units sold
+	if err := u.productRepo.IncrementSoldCount(ctx, req.ProductID, req.Quantity); err != nil {
 		return nil, err
 	}
 
 	return sale, nil
 }
 
+// takeStock reduces the stock of the product, or variant, of a sale at the
+// version it was read at and returns the product as it was read and the new stock
+func (u *SaleUseCase) takeStock(ctx context.Context, req domain.CreateSaleRequest) (*domain.Product, *domain.ProductVariant, int, error) {
+	// Get product to verify it exists and has enough stock
+	product, err := u.productRepo.GetByID(ctx, req.ProductID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if product == nil {
+		return nil, nil, 0, errors.New("product not found")
+	}
+
+	variant, err := resolveSaleVariant(product, req.VariantID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	// Check if there's enough stock
+	available := product.Stock
+	if variant != nil {
+		available = variant.Stock
+	}
+	if available < req.Quantity {
+		return nil, nil, 0, errors.New("insufficient stock")
+	}
+
+	// Update product stock
+	newStock := available - req.Quantity
+	if variant != nil {
+		err = u.productRepo.UpdateVariantStock(ctx, req.ProductID, product.Version, variant.ID, newStock)
+	} else {
+		err = u.productRepo.UpdateStock(ctx, req.ProductID, product.Version, newStock)
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return product, variant, newStock, nil
+}
+
 // GetSalesByFilter retrieves sales with filtering
 func (u *SaleUseCase) GetSalesByFilter(ctx context.Context, filter domain.SaleFilter) ([]*domain.Sale, error) {
+	if err := validateSaleListFilter(&filter); err != nil {
+		return nil, err
+	}
 	return u.saleRepo.List(ctx, filter)
 }
 
+// GetSalesByCursor retrieves a page of sales using keyset pagination and
+// returns the cursor of the next page, which is empty on the last page
+func (u *SaleUseCase) GetSalesByCursor(ctx context.Context, filter domain.SaleFilter) ([]*domain.Sale, string, error) {
+	if err := validateSaleListFilter(&filter); err != nil {
+		return nil, "", err
+	}
+	return u.saleRepo.ListWithCursor(ctx, filter)
+}
+
+// validateSaleListFilter checks the sort of a sale filter and applies the
+// default and maximum page size
+func validateSaleListFilter(filter *domain.SaleFilter) error {
+	switch filter.Sort {
+	case "", "date_sold", "total", "quantity":
+	default:
+		return errors.New("invalid sort field")
+	}
+	if err := validateSortOrder(filter.Order); err != nil {
+		return err
+	}
+
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > domain.MaxPageLimit {
+		filter.Limit = domain.MaxPageLimit
+	}
+	return nil
+}
+
 // GetSalesSummary retrieves sales summary for a period
 func (u *SaleUseCase) GetSalesSummary(ctx context.Context, fromDate, toDate time.Time) (*domain.SalesSummary, error) {
 	// If no dates provided, use current month
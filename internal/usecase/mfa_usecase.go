@@ -0,0 +1,360 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/utils"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Two-factor authentication settings
+const (
+	mfaChallengeTTL       = 5 * time.Minute
+	mfaRecoveryCodeCount  = 10
+	mfaRecoveryCodeLength = 10 // characters, shown as two groups of five
+)
+
+// SetupMFA starts two-factor authentication enrollment for a user. The new
+// secret only becomes active once EnableMFA confirms a code generated from it.
+func (u *AuthUseCase) SetupMFA(ctx context.Context, userID primitive.ObjectID) (*domain.MFASetupResponse, error) {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, errors.New("MFA is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFAPendingSecret, err = utils.EncryptSecret(u.config.MFASecretKey, secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &domain.MFASetupResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(u.config.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// EnableMFA completes enrollment with a code from the authenticator app and
+// returns the recovery codes, which are only shown once
+func (u *AuthUseCase) EnableMFA(ctx context.Context, userID primitive.ObjectID, req domain.MFACodeRequest) (*domain.MFARecoveryCodesResponse, error) {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.MFAEnabled {
+		return nil, errors.New("MFA is already enabled")
+	}
+	if user.MFAPendingSecret == "" {
+		return nil, errors.New("MFA setup has not been started")
+	}
+
+	secret, err := utils.DecryptSecret(u.config.MFASecretKey, user.MFAPendingSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := utils.ValidateTOTP(secret, req.Code, time.Now())
+	if !ok {
+		return nil, errors.New("invalid MFA code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.MFAEnabled = true
+	user.MFAEnabledAt = &now
+	user.MFASecret, err = utils.EncryptSecret(u.config.MFASecretKey, secret)
+	if err != nil {
+		return nil, err
+	}
+	user.MFAPendingSecret = ""
+	user.MFARecoveryCodes = hashes
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// The code used for enrollment cannot be used again to log in
+	if _, err := u.userRepo.UseMFAStep(ctx, user.ID, step); err != nil {
+		return nil, err
+	}
+
+	return &domain.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableMFA turns off two-factor authentication after checking the password
+// and a code. Users whose role requires MFA cannot disable it.
+func (u *AuthUseCase) DisableMFA(ctx context.Context, userID primitive.ObjectID, req domain.MFADisableRequest) error {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.MFAEnabled {
+		return errors.New("MFA is not enabled")
+	}
+	if u.isMFARequired(user.Role) {
+		return errors.New("MFA is required for this role")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+
+	if err := u.verifyMFACode(ctx, user, req.Code, "", req.IPAddress, req.UserAgent); err != nil {
+		return err
+	}
+
+	user.MFAEnabled = false
+	user.MFAEnabledAt = nil
+	user.MFASecret = ""
+	user.MFAPendingSecret = ""
+	user.MFARecoveryCodes = nil
+	return u.userRepo.Update(ctx, user)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user after checking a code
+func (u *AuthUseCase) RegenerateRecoveryCodes(ctx context.Context, userID primitive.ObjectID, req domain.MFACodeRequest) (*domain.MFARecoveryCodesResponse, error) {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled {
+		return nil, errors.New("MFA is not enabled")
+	}
+
+	if err := u.verifyMFACode(ctx, user, req.Code, "", req.IPAddress, req.UserAgent); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Saving the whole user would write back the time step of the code just
+	// accepted as it was before, and the code could be used again
+	if err := u.userRepo.SetRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &domain.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyMFA completes a login with the MFA token from Login and a code from
+// the authenticator app or a recovery code
+func (u *AuthUseCase) VerifyMFA(ctx context.Context, req domain.MFAVerifyRequest) (*domain.LoginResponse, error) {
+	if req.Code == "" && req.RecoveryCode == "" {
+		return nil, errors.New("code or recovery code required")
+	}
+
+	claims, err := u.parseClaims(req.MFAToken)
+	if err != nil || claims["typ"] != "mfa" {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+
+	tokenID, _ := claims["jti"].(string)
+	revoked, err := u.IsTokenRevoked(ctx, tokenID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+
+	userIDStr, _ := claims["user_id"].(string)
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive || !user.MFAEnabled {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+
+	if err := u.verifyMFACode(ctx, user, req.Code, req.RecoveryCode, req.IPAddress, req.UserAgent); err != nil {
+		return nil, err
+	}
+
+	// The challenge can only be completed once
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return nil, errors.New("invalid or expired MFA token")
+	}
+	err = u.tokenRepo.RevokeAccessToken(ctx, &domain.RevokedToken{
+		JTI:       tokenID,
+		UserID:    user.ID,
+		ExpiresAt: expiresAt.Time,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.clearFailedLogins(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return u.startSession(ctx, user, req.IPAddress, req.UserAgent)
+}
+
+// issueMFAChallenge creates the short-lived token that proves the password step of a login
+func (u *AuthUseCase) issueMFAChallenge(user *domain.User) (*domain.MFAChallengeResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(mfaChallengeTTL)
+
+	token, err := u.signClaims(jwt.MapClaims{
+		"jti":     uuid.New().String(),
+		"typ":     "mfa",
+		"user_id": user.ID.Hex(),
+		"exp":     expiresAt.Unix(),
+		"iat":     now.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// verifyMFACode checks an authenticator or recovery code. Codes are
+// throttled like passwords, wherever they are asked for, so neither a
+// password nor an access token is enough to guess them.
+func (u *AuthUseCase) verifyMFACode(ctx context.Context, user *domain.User, code, recoveryCode, ipAddress, userAgent string) error {
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		return &LoginThrottledError{
+			RetryAfter: time.Until(*user.LockedUntil),
+			Locked:     user.FailedLoginAttempts >= u.config.MaxFailedLogins,
+		}
+	}
+
+	ok, err := u.checkMFACode(ctx, user, code, recoveryCode)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		attempt := domain.LoginRequest{Email: user.Email, IPAddress: ipAddress, UserAgent: userAgent}
+		if err := u.handleFailedLogin(ctx, attempt, user, domain.LoginFailureInvalidMFACode); err != nil {
+			return err
+		}
+		return errors.New("invalid MFA code")
+	}
+	return nil
+}
+
+// checkMFACode checks an authenticator code or, when given, a recovery code.
+// Accepted codes are consumed so they cannot be replayed.
+func (u *AuthUseCase) checkMFACode(ctx context.Context, user *domain.User, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return u.userRepo.ConsumeRecoveryCode(ctx, user.ID, hashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+
+	secret, err := utils.DecryptSecret(u.config.MFASecretKey, user.MFASecret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return u.userRepo.UseMFAStep(ctx, user.ID, step)
+}
+
+// EncryptLegacyMFASecrets encrypts the TOTP secrets that were stored in
+// plaintext before secrets were encrypted
+func (u *AuthUseCase) EncryptLegacyMFASecrets(ctx context.Context) (int64, error) {
+	users, err := u.userRepo.ListWithPlaintextMFASecrets(ctx, utils.EncryptedSecretPrefix)
+	if err != nil {
+		return 0, err
+	}
+
+	var encrypted int64
+	for _, user := range users {
+		for _, secret := range []*string{&user.MFASecret, &user.MFAPendingSecret} {
+			if *secret == "" || strings.HasPrefix(*secret, utils.EncryptedSecretPrefix) {
+				continue
+			}
+			if *secret, err = utils.EncryptSecret(u.config.MFASecretKey, *secret); err != nil {
+				return encrypted, err
+			}
+		}
+		if err := u.userRepo.Update(ctx, user); err != nil {
+			return encrypted, err
+		}
+		encrypted++
+	}
+	return encrypted, nil
+}
+
+// isMFARequired reports whether users with the role must use two-factor authentication
+func (u *AuthUseCase) isMFARequired(role string) bool {
+	for _, required := range u.config.MFARequiredRoles {
+		if required == role {
+			return true
+		}
+	}
+	return false
+}
+
+// getUser retrieves a user by ID
+func (u *AuthUseCase) getUser(ctx context.Context, id primitive.ObjectID) (*domain.User, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// generateRecoveryCodes generates a set of recovery codes and their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	hashes := make([]string, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		raw := strings.ToLower(encoding.EncodeToString(b))[:mfaRecoveryCodeLength]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode strips formatting from a recovery code as typed by a user
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,290 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// oidcStateTTL is how long a login at the identity provider may take
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCGroupRole maps a group at the identity provider to a role
+type OIDCGroupRole struct {
+	Group string
+	Role  string
+}
+
+// OIDCConfig holds provisioning settings for logins through an identity provider
+type OIDCConfig struct {
+	GroupRoles     []OIDCGroupRole // Checked in order, the first group the user is in decides the role
+	DefaultRole    string          // Role of users in none of the mapped groups
+	AllowedDomains []string        // Email domains that may log in, any when empty
+	AutoProvision  bool            // Create users that do not exist yet
+}
+
+// IdentityProviderError is returned when the identity provider refuses or
+// fails a login
+type IdentityProviderError struct {
+	Err error
+}
+
+func (e *IdentityProviderError) Error() string {
+	return "identity provider login failed: " + e.Err.Error()
+}
+
+func (e *IdentityProviderError) Unwrap() error {
+	return e.Err
+}
+
+// OIDCUseCase handles logins through an OpenID Connect identity provider
+type OIDCUseCase struct {
+	authUseCase *AuthUseCase
+	userRepo    domain.UserRepository
+	roleRepo    domain.RoleRepository
+	stateRepo   domain.OIDCStateRepository
+	provider    domain.IdentityProvider
+	config      OIDCConfig
+}
+
+// NewOIDCUseCase creates a new OpenID Connect use case
+func NewOIDCUseCase(authUseCase *AuthUseCase, userRepo domain.UserRepository, roleRepo domain.RoleRepository, stateRepo domain.OIDCStateRepository, provider domain.IdentityProvider, config OIDCConfig) *OIDCUseCase {
+	if config.DefaultRole == "" {
+		config.DefaultRole = domain.RoleUser
+	}
+
+	return &OIDCUseCase{
+		authUseCase: authUseCase,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		stateRepo:   stateRepo,
+		provider:    provider,
+		config:      config,
+	}
+}
+
+// StartLogin creates the state, nonce and PKCE verifier of a new login and
+// returns the URL that sends the user to the identity provider. The state is
+// returned too, for the browser to keep in a cookie.
+func (u *OIDCUseCase) StartLogin(ctx context.Context) (*domain.OIDCAuthorizationResponse, error) {
+	state, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(oidcStateTTL)
+	err = u.stateRepo.Create(ctx, &domain.OIDCState{
+		StateHash:    hashToken(state),
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	authorizationURL, err := u.provider.AuthCodeURL(ctx, state, nonce, codeChallenge(codeVerifier))
+	if err != nil {
+		return nil, &IdentityProviderError{Err: err}
+	}
+
+	return &domain.OIDCAuthorizationResponse{
+		AuthorizationURL: authorizationURL,
+		ExpiresAt:        expiresAt,
+		State:            state,
+	}, nil
+}
+
+// CompleteLogin redeems the code the identity provider redirected back with,
+// finds, links or provisions the user and starts a session. Users with
+// two-factor authentication get an MFA challenge instead, as with a password
+// login. The state must match the one kept by the browser that started the
+// login, so a victim cannot be signed in to an attacker's account with a
+// callback link the attacker started.
+func (u *OIDCUseCase) CompleteLogin(ctx context.Context, req domain.OIDCCallbackRequest) (*domain.LoginResponse, *domain.MFAChallengeResponse, error) {
+	if subtle.ConstantTimeCompare([]byte(req.State), []byte(req.Browser)) != 1 {
+		return nil, nil, errors.New("login was not started in this browser")
+	}
+
+	stored, err := u.stateRepo.Consume(ctx, hashToken(req.State))
+	if err != nil {
+		return nil, nil, err
+	}
+	if stored == nil {
+		return nil, nil, errors.New("invalid or expired login state")
+	}
+
+	identity, err := u.provider.Exchange(ctx, req.Code, stored.CodeVerifier)
+	if err != nil {
+		return nil, nil, &IdentityProviderError{Err: err}
+	}
+	if identity.Nonce != stored.Nonce {
+		return nil, nil, &IdentityProviderError{Err: errors.New("nonce mismatch")}
+	}
+
+	user, err := u.findOrProvisionUser(ctx, identity)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, errors.New("user account is inactive")
+	}
+
+	if user.MFAEnabled {
+		challenge, err := u.authUseCase.issueMFAChallenge(user)
+		return nil, challenge, err
+	}
+
+	response, err := u.authUseCase.startSession(ctx, user, req.IPAddress, req.UserAgent)
+	return response, nil, err
+}
+
+// findOrProvisionUser returns the user linked to an identity. Unlinked
+// identities are linked to the user with the same email, or provisioned as a
+// new user. When group mappings are configured the role follows the groups
+// of the identity for provisioned users; linked accounts keep the role they
+// were given in the store.
+func (u *OIDCUseCase) findOrProvisionUser(ctx context.Context, identity *domain.ExternalIdentity) (*domain.User, error) {
+	if !u.isAllowedDomain(identity.Email) {
+		return nil, errors.New("email domain is not allowed")
+	}
+
+	user, err := u.userRepo.GetByOIDCSubject(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	// Emails are only trusted for linking and provisioning once the provider verified them
+	if user == nil {
+		if identity.Email == "" || !identity.EmailVerified {
+			return nil, errors.New("email is not verified by the identity provider")
+		}
+
+		user, err = u.userRepo.GetByEmail(ctx, identity.Email)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil && !u.config.AutoProvision {
+			return nil, errors.New("no account exists for this email")
+		}
+	}
+
+	role, err := u.mapRole(ctx, identity.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		// Provisioned users have no password; they can set one with a password reset
+		now := time.Now()
+		user = &domain.User{
+			Email:           identity.Email,
+			Name:            identity.Name,
+			Role:            role,
+			IsActive:        true,
+			EmailVerifiedAt: &now,
+			OIDCIssuer:      identity.Issuer,
+			OIDCSubject:     identity.Subject,
+			OIDCManaged:     true,
+		}
+		if user.Name == "" {
+			user.Name = identity.Email
+		}
+		if user.Role == "" {
+			user.Role = u.config.DefaultRole
+		}
+
+		if err := u.userRepo.Create(ctx, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	user.OIDCIssuer = identity.Issuer
+	user.OIDCSubject = identity.Subject
+	if role != "" && user.OIDCManaged {
+		user.Role = role
+	}
+	if user.EmailVerifiedAt == nil && identity.EmailVerified && identity.Email == user.Email {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// mapRole returns the role for the groups of a user. It returns an empty
+// role when no group mappings are configured, leaving existing roles alone.
+func (u *OIDCUseCase) mapRole(ctx context.Context, groups []string) (string, error) {
+	if len(u.config.GroupRoles) == 0 {
+		return "", nil
+	}
+
+	role := u.config.DefaultRole
+	for _, mapping := range u.config.GroupRoles {
+		if containsString(groups, mapping.Group) {
+			role = mapping.Role
+			break
+		}
+	}
+
+	existing, err := u.roleRepo.GetByName(ctx, role)
+	if err != nil {
+		return "", err
+	}
+	if existing == nil {
+		return "", errors.New("mapped role does not exist")
+	}
+	return role, nil
+}
+
+// isAllowedDomain reports whether users with the email may log in
+func (u *OIDCUseCase) isAllowedDomain(email string) bool {
+	if len(u.config.AllowedDomains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domainName := strings.ToLower(email[at+1:])
+	for _, allowed := range u.config.AllowedDomains {
+		if strings.ToLower(allowed) == domainName {
+			return true
+		}
+	}
+	return false
+}
+
+// codeChallenge derives the S256 PKCE challenge of a verifier
+func codeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// containsString reports whether a list contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
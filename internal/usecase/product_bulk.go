@@ -0,0 +1,268 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"errors"
+	"math"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultPriceRounding rounds adjusted prices to cents
+const defaultPriceRounding = 0.01
+
+// maxBulkSaveAttempts is how often a bulk update saves a product before
+// giving up on concurrent changes to it
+const maxBulkSaveAttempts = 3
+
+// errTooManyBulkProducts stops collecting products for a bulk update
+var errTooManyBulkProducts = errors.New("too many products, narrow the filter")
+
+// BulkUpdateProducts changes the price, active status, category or brand of
+// the products given by ID or matching a filter. All changes are checked
+// before any product is saved, and a preview only returns the changes.
+// Products that cannot be saved are reported on their change and do not stop
+// the products after them.
+func (u *ProductUseCase) BulkUpdateProducts(ctx context.Context, req domain.BulkProductRequest) (*domain.BulkProductResult, error) {
+	if err := validateBulkRequest(&req); err != nil {
+		return nil, err
+	}
+
+	products, err := u.bulkProducts(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.BulkProductResult{
+		Preview:  req.Preview,
+		Matched:  len(products),
+		Products: []domain.BulkProductChange{},
+	}
+	var befores []*domain.Product
+	var changed []*domain.Product
+	for _, product := range products {
+		before := cloneProduct(product)
+		if err := applyBulkChanges(product, req); err != nil {
+			return nil, err
+		}
+
+		changes := diffProducts(before, product)
+		if len(changes) == 0 {
+			continue
+		}
+		result.Products = append(result.Products, domain.BulkProductChange{
+			ProductID: product.ID,
+			SKU:       product.SKU,
+			Name:      product.Name,
+			Changes:   changes,
+		})
+		befores = append(befores, before)
+		changed = append(changed, product)
+	}
+	result.Changed = len(changed)
+
+	if req.Preview {
+		return result, nil
+	}
+	for i, product := range changed {
+		change, err := u.saveBulkChange(ctx, befores[i], product, req)
+		if err != nil {
+			return nil, err
+		}
+		result.Products[i] = change
+		if change.Error != "" {
+			result.Failed++
+		}
+	}
+	result.Changed -= result.Failed
+
+	return result, nil
+}
+
+// saveBulkChange saves the bulk changes of a product. A product that was
+// changed by someone else since it was read is read again and the changes are
+// applied to its current version. When it keeps changing, was deleted or no
+// longer takes the changes, the reason is returned on the change; the
+// returned error is a database failure.
+func (u *ProductUseCase) saveBulkChange(ctx context.Context, before, product *domain.Product, req domain.BulkProductRequest) (domain.BulkProductChange, error) {
+	change := domain.BulkProductChange{
+		ProductID: product.ID,
+		SKU:       product.SKU,
+		Name:      product.Name,
+		Changes:   diffProducts(before, product),
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := u.updateProduct(ctx, before, product)
+		if err == nil {
+			return change, nil
+		}
+		if err.Error() != "version mismatch" {
+			return change, err
+		}
+		if attempt == maxBulkSaveAttempts {
+			change.Error = "product was changed during the bulk update"
+			return change, nil
+		}
+
+		current, err := u.productRepo.GetByID(ctx, product.ID)
+		if err != nil {
+			return change, err
+		}
+		if current == nil {
+			change.Error = "product not found"
+			return change, nil
+		}
+		before, product = cloneProduct(current), current
+		if err := applyBulkChanges(product, req); err != nil {
+			change.Error = err.Error()
+			return change, nil
+		}
+		change.SKU, change.Name, change.Changes = product.SKU, product.Name, diffProducts(before, product)
+		// The other change already made this one
+		if len(change.Changes) == 0 {
+			return change, nil
+		}
+	}
+}
+
+// bulkProducts retrieves the products of a bulk update
+func (u *ProductUseCase) bulkProducts(ctx context.Context, req domain.BulkProductRequest) ([]*domain.Product, error) {
+	if req.Filter == nil {
+		ids := make([]primitive.ObjectID, 0, len(req.IDs))
+		seen := make(map[primitive.ObjectID]bool, len(req.IDs))
+		for _, hex := range req.IDs {
+			id, err := primitive.ObjectIDFromHex(hex)
+			if err != nil {
+				return nil, errors.New("invalid product ID")
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+
+		products, err := u.productRepo.ListByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		if len(products) != len(ids) {
+			return nil, errors.New("product not found")
+		}
+		return products, nil
+	}
+
+	// Pagination does not apply and the trash is never changed
+	filter := *req.Filter
+	filter.Page, filter.Limit, filter.Cursor, filter.Deleted = 0, 0, nil, false
+	if err := validateProductListFilter(&filter); err != nil {
+		return nil, err
+	}
+	filter, _, err := u.resolveSearch(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var products []*domain.Product
+	err = u.productRepo.ForEach(ctx, filter, func(product *domain.Product) error {
+		if len(products) == domain.MaxBulkProducts {
+			return errTooManyBulkProducts
+		}
+		products = append(products, product)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+// validateBulkRequest checks that a bulk update selects products and changes
+// something, and applies the default price rounding
+func validateBulkRequest(req *domain.BulkProductRequest) error {
+	if (req.Filter == nil) == (len(req.IDs) == 0) {
+		return errors.New("either ids or filter is required")
+	}
+	if len(req.IDs) > domain.MaxBulkProducts {
+		return errTooManyBulkProducts
+	}
+
+	req.Category = strings.TrimSpace(req.Category)
+	req.Brand = strings.TrimSpace(req.Brand)
+	if req.Price == nil && req.IsActive == nil && req.Category == "" && req.Brand == "" {
+		return errors.New("no changes requested")
+	}
+
+	if price := req.Price; price != nil {
+		if (price.Percent == nil) == (price.Amount == nil) {
+			return errors.New("price adjustment requires either percent or amount")
+		}
+		if price.Percent != nil && *price.Percent <= -100 {
+			return errors.New("price percent must be greater than -100")
+		}
+		if price.RoundTo == 0 {
+			price.RoundTo = defaultPriceRounding
+		}
+		if price.RoundTo < defaultPriceRounding {
+			return errors.New("price rounding must be at least 0.01")
+		}
+	}
+
+	return nil
+}
+
+// applyBulkChanges applies the changes of a bulk update to a product. The
+// price adjustment applies to each variant of products with variants.
+func applyBulkChanges(product *domain.Product, req domain.BulkProductRequest) error {
+	if req.Price != nil {
+		if len(product.Variants) == 0 {
+			price, err := adjustPrice(product.Price, req.Price)
+			if err != nil {
+				return err
+			}
+			product.Price = price
+		} else {
+			for i := range product.Variants {
+				price, err := adjustPrice(product.Variants[i].Price, req.Price)
+				if err != nil {
+					return err
+				}
+				product.Variants[i].Price = price
+			}
+			// Updates the product price from the variants
+			if err := applyVariants(product, nil); err != nil {
+				return err
+			}
+		}
+	}
+	if req.IsActive != nil {
+		product.IsActive = *req.IsActive
+	}
+	if req.Category != "" {
+		product.Category = req.Category
+	}
+	if req.Brand != "" {
+		product.Brand = req.Brand
+	}
+	return nil
+}
+
+// adjustPrice applies a price adjustment and rounds the result to a multiple
+// of its rounding step
+func adjustPrice(price float64, adjustment *domain.PriceAdjustment) (float64, error) {
+	if adjustment.Percent != nil {
+		price *= 1 + *adjustment.Percent/100
+	} else {
+		price += *adjustment.Amount
+	}
+
+	price = math.Round(price/adjustment.RoundTo) * adjustment.RoundTo
+	// Drop the floating point noise of the multiplication, such as 104.99999999999999
+	price = math.Round(price*100) / 100
+	if price <= 0 {
+		return 0, errors.New("adjusted price must be greater than 0")
+	}
+	return price, nil
+}
@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/utils"
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// maxInternalBarcodeLength is the longest store-assigned barcode accepted
+const maxInternalBarcodeLength = 64
+
+// InvalidBarcodeError is returned when a barcode has the wrong format or check digit
+type InvalidBarcodeError struct {
+	Code   string
+	Reason string
+}
+
+func (e *InvalidBarcodeError) Error() string {
+	return "invalid barcode " + e.Code + ": " + e.Reason
+}
+
+// LookupProduct finds the product, and variant, a scanned SKU or barcode belongs to
+func (u *ProductUseCase) LookupProduct(ctx context.Context, code string) (*domain.ProductLookupResult, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return nil, errors.New("code is required")
+	}
+
+	codes := lookupCodes(code)
+	products, err := u.productRepo.ListByCodes(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, product := range products {
+		// Products in the trash keep their codes until they are purged
+		if product.DeletedAt != nil {
+			continue
+		}
+		for _, code := range codes {
+			if product.SKU == code {
+				return &domain.ProductLookupResult{Product: product, MatchedBy: "sku"}, nil
+			}
+			if hasBarcode(product.Barcodes, code) {
+				return &domain.ProductLookupResult{Product: product, MatchedBy: "barcode"}, nil
+			}
+			for i := range product.Variants {
+				variant := &product.Variants[i]
+				if variant.SKU == code {
+					return &domain.ProductLookupResult{Product: product, Variant: variant, MatchedBy: "sku"}, nil
+				}
+				if hasBarcode(variant.Barcodes, code) {
+					return &domain.ProductLookupResult{Product: product, Variant: variant, MatchedBy: "barcode"}, nil
+				}
+			}
+		}
+	}
+
+	return nil, errors.New("product not found")
+}
+
+// applyCodes sets the SKU and barcodes of a product and checks that none of
+// its codes is used twice or belongs to another product. Codes are compared
+// like lookups find them, so a UPC-A code and its EAN-13 form are the same
+// code. An empty SKU keeps the current one and a nil list keeps the current
+// barcodes.
+func (u *ProductUseCase) applyCodes(ctx context.Context, product *domain.Product, sku string, barcodes []domain.ProductBarcode) error {
+	if sku = strings.TrimSpace(sku); sku != "" {
+		product.SKU = sku
+	}
+	if barcodes != nil {
+		normalized, err := normalizeBarcodes(barcodes)
+		if err != nil {
+			return err
+		}
+		product.Barcodes = normalized
+	}
+
+	skus, barcodeCodes := productCodes(product)
+	kinds := make(map[string]string, len(skus)+len(barcodeCodes))
+	for _, code := range skus {
+		if _, ok := kinds[canonicalCode(code)]; ok {
+			return errors.New("duplicate SKU")
+		}
+		kinds[canonicalCode(code)] = "sku"
+	}
+	for _, code := range barcodeCodes {
+		if _, ok := kinds[canonicalCode(code)]; ok {
+			return errors.New("duplicate barcode")
+		}
+		kinds[canonicalCode(code)] = "barcode"
+	}
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, 2*len(kinds))
+	for code := range kinds {
+		codes = append(codes, lookupCodes(code)...)
+	}
+
+	others, err := u.productRepo.ListByCodes(ctx, codes)
+	if err != nil {
+		return err
+	}
+	for _, other := range others {
+		if other.ID == product.ID {
+			continue
+		}
+		otherSKUs, otherBarcodes := productCodes(other)
+		for _, code := range append(otherSKUs, otherBarcodes...) {
+			switch kinds[canonicalCode(code)] {
+			case "sku":
+				return errors.New("SKU already exists")
+			case "barcode":
+				return errors.New("barcode already exists")
+			}
+		}
+	}
+
+	return nil
+}
+
+// productCodes returns the SKUs and barcodes of a product and its variants
+func productCodes(product *domain.Product) ([]string, []string) {
+	var skus, barcodes []string
+	if product.SKU != "" {
+		skus = append(skus, product.SKU)
+	}
+	for _, barcode := range product.Barcodes {
+		barcodes = append(barcodes, barcode.Code)
+	}
+	for _, variant := range product.Variants {
+		skus = append(skus, variant.SKU)
+		for _, barcode := range variant.Barcodes {
+			barcodes = append(barcodes, barcode.Code)
+		}
+	}
+	return skus, barcodes
+}
+
+// normalizeBarcodes trims barcodes, detects missing types and validates them
+func normalizeBarcodes(barcodes []domain.ProductBarcode) ([]domain.ProductBarcode, error) {
+	normalized := make([]domain.ProductBarcode, 0, len(barcodes))
+	for _, barcode := range barcodes {
+		code := strings.TrimSpace(barcode.Code)
+		barcodeType := strings.ToLower(strings.TrimSpace(barcode.Type))
+		if barcodeType == "" {
+			barcodeType = detectBarcodeType(code)
+		}
+
+		if err := validateBarcode(code, barcodeType); err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, domain.ProductBarcode{Code: code, Type: barcodeType})
+	}
+	return normalized, nil
+}
+
+// detectBarcodeType returns the type a code looks like: EAN-13 for 13 digits,
+// UPC-A for 12 digits and internal for anything else
+func detectBarcodeType(code string) string {
+	if utils.IsDigits(code) {
+		switch len(code) {
+		case 13:
+			return domain.BarcodeTypeEAN13
+		case 12:
+			return domain.BarcodeTypeUPC
+		}
+	}
+	return domain.BarcodeTypeInternal
+}
+
+// validateBarcode checks the length and check digit of a barcode
+func validateBarcode(code, barcodeType string) error {
+	invalid := func(reason string) error {
+		return &InvalidBarcodeError{Code: code, Reason: reason}
+	}
+
+	switch barcodeType {
+	case domain.BarcodeTypeEAN13:
+		if len(code) != 13 || !utils.IsDigits(code) {
+			return invalid("EAN-13 barcodes have 13 digits")
+		}
+		if !utils.ValidGTINCheckDigit(code) {
+			return invalid("wrong check digit")
+		}
+	case domain.BarcodeTypeUPC:
+		if len(code) != 12 || !utils.IsDigits(code) {
+			return invalid("UPC-A barcodes have 12 digits")
+		}
+		if !utils.ValidGTINCheckDigit(code) {
+			return invalid("wrong check digit")
+		}
+	case domain.BarcodeTypeInternal:
+		if code == "" {
+			return invalid("code is required")
+		}
+		if len(code) > maxInternalBarcodeLength {
+			return invalid("internal barcodes have at most 64 characters")
+		}
+		if strings.ContainsFunc(code, unicode.IsSpace) {
+			return invalid("barcodes cannot contain spaces")
+		}
+	default:
+		return invalid("unknown barcode type " + barcodeType)
+	}
+	return nil
+}
+
+// lookupCodes returns the code as scanned plus its UPC-A or EAN-13 form,
+// since scanners report UPC-A codes with or without a leading zero
+func lookupCodes(code string) []string {
+	codes := []string{code}
+	if utils.IsDigits(code) {
+		switch {
+		case len(code) == 13 && code[0] == '0':
+			codes = append(codes, code[1:])
+		case len(code) == 12:
+			codes = append(codes, "0"+code)
+		}
+	}
+	return codes
+}
+
+// canonicalCode returns the form of a code that lookups treat it the same as:
+// EAN-13 codes with a leading zero are compared as their UPC-A code
+func canonicalCode(code string) string {
+	if len(code) == 13 && code[0] == '0' && utils.IsDigits(code) {
+		return code[1:]
+	}
+	return code
+}
+
+// hasBarcode reports whether a code is one of the barcodes
+func hasBarcode(barcodes []domain.ProductBarcode, code string) bool {
+	for _, barcode := range barcodes {
+		if barcode.Code == code {
+			return true
+		}
+	}
+	return false
+}
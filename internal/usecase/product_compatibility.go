@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Model years accepted in fitments
+const (
+	minFitmentYear = 1900
+	maxFitmentYear = 2100
+)
+
+// InvalidCompatibilityError is returned for a bulk compatibility change that
+// cannot be applied. The reason names the mapping, e.g. set[2].
+type InvalidCompatibilityError struct {
+	Reason string
+}
+
+func (e *InvalidCompatibilityError) Error() string {
+	return "invalid compatibility: " + e.Reason
+}
+
+// GetCompatibleParts retrieves the parts that fit a machine, sorted by name.
+// A model year and serial number leave out parts whose fitment excludes
+// them, and a category keeps only the parts in it.
+func (u *ProductUseCase) GetCompatibleParts(ctx context.Context, machineID primitive.ObjectID, filter domain.CompatibilityFilter) ([]domain.CompatibleProduct, error) {
+	if _, err := u.GetProductByID(ctx, machineID); err != nil {
+		return nil, err
+	}
+
+	mappings, err := u.compatibilityRepo.ListByMachine(ctx, machineID)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []*domain.PartCompatibility
+	for _, mapping := range mappings {
+		if fitsMachine(mapping.Fitment, filter.Year, strings.TrimSpace(filter.Serial)) {
+			matching = append(matching, mapping)
+		}
+	}
+
+	return u.compatibleProducts(ctx, matching, func(mapping *domain.PartCompatibility) primitive.ObjectID {
+		return mapping.PartID
+	}, filter.Category)
+}
+
+// GetPartFits retrieves the machines a part fits with their fitments, sorted by name
+func (u *ProductUseCase) GetPartFits(ctx context.Context, partID primitive.ObjectID) ([]domain.CompatibleProduct, error) {
+	if _, err := u.GetProductByID(ctx, partID); err != nil {
+		return nil, err
+	}
+
+	mappings, err := u.compatibilityRepo.ListByPart(ctx, partID)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.compatibleProducts(ctx, mappings, func(mapping *domain.PartCompatibility) primitive.ObjectID {
+		return mapping.MachineID
+	}, "")
+}
+
+// compatibleProducts loads the products on one side of compatibility
+// mappings. Products in the trash are left out until they are restored.
+func (u *ProductUseCase) compatibleProducts(ctx context.Context, mappings []*domain.PartCompatibility, side func(*domain.PartCompatibility) primitive.ObjectID, category string) ([]domain.CompatibleProduct, error) {
+	result := []domain.CompatibleProduct{}
+	if len(mappings) == 0 {
+		return result, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(mappings))
+	for i, mapping := range mappings {
+		ids[i] = side(mapping)
+	}
+	products, err := u.productRepo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*domain.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	for _, mapping := range mappings {
+		product, ok := byID[side(mapping)]
+		if !ok || (category != "" && product.Category != category) {
+			continue
+		}
+		result = append(result, domain.CompatibleProduct{Product: product, Fitment: mapping.Fitment})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Product.Name < result[j].Product.Name
+	})
+
+	return result, nil
+}
+
+// BulkUpdateCompatibility sets and removes part compatibility mappings. Every
+// mapping is checked before any is saved, and the parts and machines that
+// are set must exist outside the trash.
+func (u *ProductUseCase) BulkUpdateCompatibility(ctx context.Context, req domain.CompatibilityBulkRequest) (*domain.CompatibilityBulkResult, error) {
+	if len(req.Set) == 0 && len(req.Remove) == 0 {
+		return nil, errors.New("no changes requested")
+	}
+	if len(req.Set)+len(req.Remove) > domain.MaxCompatibilityChanges {
+		return nil, errors.New("too many mappings")
+	}
+
+	// A pair given twice would depend on the order the changes are applied in
+	seen := make(map[[2]primitive.ObjectID]string, len(req.Set)+len(req.Remove))
+	checkPair := func(name string, mapping domain.CompatibilityMapping) error {
+		pair := [2]primitive.ObjectID{mapping.PartID, mapping.MachineID}
+		if first, ok := seen[pair]; ok {
+			return &InvalidCompatibilityError{Reason: fmt.Sprintf("%s: part and machine are already given in %s", name, first)}
+		}
+		seen[pair] = name
+		return nil
+	}
+
+	var ids []primitive.ObjectID
+	set := make([]*domain.PartCompatibility, 0, len(req.Set))
+	for i, mapping := range req.Set {
+		name := fmt.Sprintf("set[%d]", i)
+		if err := checkPair(name, mapping); err != nil {
+			return nil, err
+		}
+		if mapping.PartID == mapping.MachineID {
+			return nil, &InvalidCompatibilityError{Reason: name + ": a part cannot fit itself"}
+		}
+		fitment, err := validateFitment(mapping.Fitment)
+		if err != nil {
+			return nil, &InvalidCompatibilityError{Reason: name + ": " + err.Error()}
+		}
+
+		ids = append(ids, mapping.PartID, mapping.MachineID)
+		set = append(set, &domain.PartCompatibility{
+			PartID:    mapping.PartID,
+			MachineID: mapping.MachineID,
+			Fitment:   fitment,
+		})
+	}
+	for i, mapping := range req.Remove {
+		if err := checkPair(fmt.Sprintf("remove[%d]", i), mapping); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ids) > 0 {
+		products, err := u.productRepo.ListByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		exists := make(map[primitive.ObjectID]bool, len(products))
+		for _, product := range products {
+			exists[product.ID] = true
+		}
+		for i, mapping := range set {
+			if !exists[mapping.PartID] {
+				return nil, &InvalidCompatibilityError{Reason: fmt.Sprintf("set[%d]: part not found", i)}
+			}
+			if !exists[mapping.MachineID] {
+				return nil, &InvalidCompatibilityError{Reason: fmt.Sprintf("set[%d]: machine not found", i)}
+			}
+		}
+	}
+
+	removed, err := u.compatibilityRepo.Apply(ctx, set, req.Remove)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.CompatibilityBulkResult{Set: len(set), Removed: int(removed)}, nil
+}
+
+// validateFitment trims a fitment and checks that its bounds are in order
+func validateFitment(fitment domain.Fitment) (domain.Fitment, error) {
+	fitment.SerialFrom = strings.TrimSpace(fitment.SerialFrom)
+	fitment.SerialTo = strings.TrimSpace(fitment.SerialTo)
+	fitment.Notes = strings.TrimSpace(fitment.Notes)
+
+	for _, year := range []int{fitment.YearFrom, fitment.YearTo} {
+		if year != 0 && (year < minFitmentYear || year > maxFitmentYear) {
+			return fitment, fmt.Errorf("years must be between %d and %d", minFitmentYear, maxFitmentYear)
+		}
+	}
+	if fitment.YearFrom != 0 && fitment.YearTo != 0 && fitment.YearFrom > fitment.YearTo {
+		return fitment, errors.New("year_from is after year_to")
+	}
+	if fitment.SerialFrom != "" && fitment.SerialTo != "" && compareSerials(fitment.SerialFrom, fitment.SerialTo) > 0 {
+		return fitment, errors.New("serial_from is after serial_to")
+	}
+
+	return fitment, nil
+}
+
+// fitsMachine reports whether a fitment covers a machine of a model year and
+// serial number. A year of 0 and an empty serial match every fitment.
+func fitsMachine(fitment domain.Fitment, year int, serial string) bool {
+	if year != 0 {
+		if (fitment.YearFrom != 0 && year < fitment.YearFrom) || (fitment.YearTo != 0 && year > fitment.YearTo) {
+			return false
+		}
+	}
+	if serial != "" {
+		if (fitment.SerialFrom != "" && compareSerials(serial, fitment.SerialFrom) < 0) ||
+			(fitment.SerialTo != "" && compareSerials(serial, fitment.SerialTo) > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareSerials compares serial numbers without regard to case, with runs
+// of digits compared as numbers so SN9 comes before SN10
+func compareSerials(a, b string) int {
+	a, b = strings.ToUpper(a), strings.ToUpper(b)
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, nb := digitRunLength(a), digitRunLength(b)
+			da, db := strings.TrimLeft(a[:na], "0"), strings.TrimLeft(b[:nb], "0")
+			if c := cmp.Compare(len(da), len(db)); c != 0 {
+				return c
+			}
+			if c := strings.Compare(da, db); c != 0 {
+				return c
+			}
+			a, b = a[na:], b[nb:]
+			continue
+		}
+		if c := cmp.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		a, b = a[1:], b[1:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// digitRunLength returns the number of digits at the start of s
+func digitRunLength(s string) int {
+	n := 0
+	for n < len(s) && isDigit(s[n]) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/utils"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// productExportColumns are the leading columns of CSV and XLSX exports, the
+// names match the import columns. A spec.<key> column follows for every
+// spec attribute.
+var productExportColumns = []string{
+	"id", "sku", "name", "description", "category", "brand", "price", "stock",
+	"is_active", "barcodes", "image_urls", "variants",
+}
+
+// productExporter writes exported products in one format
+type productExporter interface {
+	write(product *domain.Product) error
+	close() error
+}
+
+// ExportProducts writes every product matching the filter to w as CSV, XLSX
+// or JSON, in list order and ignoring pagination. Products are written as
+// they are read, so nothing is written when the format or filter is invalid.
+func (u *ProductUseCase) ExportProducts(ctx context.Context, filter domain.ProductFilter, format string, w io.Writer) error {
+	if format != domain.ExportFormatCSV && format != domain.ExportFormatXLSX && format != domain.ExportFormatJSON {
+		return errors.New("unsupported format")
+	}
+	if err := validateProductListFilter(&filter); err != nil {
+		return err
+	}
+
+	filter, _, err := u.resolveSearch(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	attributes, err := u.specRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	specKeys := make([]string, len(attributes))
+	for i, attribute := range attributes {
+		specKeys[i] = attribute.Key
+	}
+
+	var exporter productExporter
+	switch format {
+	case domain.ExportFormatCSV:
+		exporter, err = newCSVProductExporter(w, specKeys)
+	case domain.ExportFormatXLSX:
+		exporter, err = newXLSXProductExporter(w, specKeys)
+	default:
+		exporter, err = newJSONProductExporter(w)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := u.productRepo.ForEach(ctx, filter, exporter.write); err != nil {
+		return err
+	}
+	return exporter.close()
+}
+
+// exportHeader returns the header row of CSV and XLSX exports
+func exportHeader(specKeys []string) []string {
+	header := append([]string{}, productExportColumns...)
+	for _, key := range specKeys {
+		header = append(header, importSpecPrefix+key)
+	}
+	return header
+}
+
+// exportRow returns the cells of a product in the order of exportHeader.
+// Empty cells are nil. Products with variants have their variants as a JSON
+// array instead of a price and stock, which are derived from the variants.
+func exportRow(product *domain.Product, specKeys []string) ([]interface{}, error) {
+	barcodes := make([]string, len(product.Barcodes))
+	for i, barcode := range product.Barcodes {
+		barcodes[i] = barcode.Code
+	}
+	imageURLs := make([]string, 0, len(product.Images))
+	for _, image := range product.Images {
+		if image.URL != "" {
+			imageURLs = append(imageURLs, image.URL)
+		}
+	}
+	if len(imageURLs) == 0 && product.ImageURL != "" {
+		imageURLs = append(imageURLs, product.ImageURL)
+	}
+
+	var price, stock, variants interface{} = product.Price, product.Stock, nil
+	if len(product.Variants) > 0 {
+		data, err := json.Marshal(product.Variants)
+		if err != nil {
+			return nil, err
+		}
+		price, stock, variants = nil, nil, string(data)
+	}
+
+	row := []interface{}{
+		product.ID.Hex(), product.SKU, product.Name, product.Description, product.Category, product.Brand,
+		price, stock, product.IsActive, strings.Join(barcodes, ","), strings.Join(imageURLs, ","), variants,
+	}
+	for _, key := range specKeys {
+		var value interface{}
+		for _, spec := range product.Specs {
+			if spec.Key == key {
+				value = spec.Value
+				break
+			}
+		}
+		row = append(row, value)
+	}
+
+	for i, cell := range row {
+		if cell == "" {
+			row[i] = nil
+		}
+	}
+	return row, nil
+}
+
+type csvProductExporter struct {
+	writer   *csv.Writer
+	specKeys []string
+}
+
+func newCSVProductExporter(w io.Writer, specKeys []string) (*csvProductExporter, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(exportHeader(specKeys)); err != nil {
+		return nil, err
+	}
+	return &csvProductExporter{writer: writer, specKeys: specKeys}, nil
+}
+
+func (e *csvProductExporter) write(product *domain.Product) error {
+	row, err := exportRow(product, e.specKeys)
+	if err != nil {
+		return err
+	}
+	record := make([]string, len(row))
+	for i, cell := range row {
+		switch v := cell.(type) {
+		case nil:
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case string:
+			record[i] = utils.EscapeCSVFormula(v)
+		default:
+			record[i] = fmt.Sprint(v)
+		}
+	}
+	return e.writer.Write(record)
+}
+
+func (e *csvProductExporter) close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type xlsxProductExporter struct {
+	writer   *utils.XLSXWriter
+	specKeys []string
+}
+
+func newXLSXProductExporter(w io.Writer, specKeys []string) (*xlsxProductExporter, error) {
+	writer, err := utils.NewXLSXWriter(w, "Products")
+	if err != nil {
+		return nil, err
+	}
+
+	header := exportHeader(specKeys)
+	cells := make([]interface{}, len(header))
+	for i, name := range header {
+		cells[i] = name
+	}
+	if err := writer.WriteRow(cells); err != nil {
+		return nil, err
+	}
+	return &xlsxProductExporter{writer: writer, specKeys: specKeys}, nil
+}
+
+func (e *xlsxProductExporter) write(product *domain.Product) error {
+	row, err := exportRow(product, e.specKeys)
+	if err != nil {
+		return err
+	}
+	return e.writer.WriteRow(row)
+}
+
+func (e *xlsxProductExporter) close() error {
+	return e.writer.Close()
+}
+
+// jsonProductExporter writes a JSON array of products with all their fields
+type jsonProductExporter struct {
+	w     io.Writer
+	count int
+}
+
+func newJSONProductExporter(w io.Writer) (*jsonProductExporter, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return nil, err
+	}
+	return &jsonProductExporter{w: w}, nil
+}
+
+func (e *jsonProductExporter) write(product *domain.Product) error {
+	data, err := json.Marshal(product)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonProductExporter) close() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
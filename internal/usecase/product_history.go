@@ -0,0 +1,309 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/logger"
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// priceFields are the revision fields that change a product or variant price
+var priceFields = []string{"price", "variants", "variants.price"}
+
+// GetProductHistory retrieves the revisions of a product, newest first.
+// Products in the trash keep their history.
+func (u *ProductUseCase) GetProductHistory(ctx context.Context, id primitive.ObjectID, page, limit int) ([]*domain.ProductRevision, int64, error) {
+	if _, err := u.getProductOrDeleted(ctx, id); err != nil {
+		return nil, 0, err
+	}
+
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	if limit > domain.MaxPageLimit {
+		limit = domain.MaxPageLimit
+	}
+
+	revisions, err := u.revisionRepo.ListByProduct(ctx, id, page, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := u.revisionRepo.CountByProduct(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, count, nil
+}
+
+// GetPriceHistory retrieves the price series of a product and its variants
+// between two times, zero meaning unbounded. A series starts with the price
+// in effect at from.
+func (u *ProductUseCase) GetPriceHistory(ctx context.Context, id primitive.ObjectID, from, to time.Time) (*domain.PriceHistory, error) {
+	product, err := u.getProductOrDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := u.revisionRepo.ListByFields(ctx, id, priceFields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Series are kept in the order they first appear, the product first
+	series := map[string]*domain.PriceSeries{"": {Name: product.Name}}
+	order := []string{""}
+	seriesFor := func(variantID, name string) *domain.PriceSeries {
+		if s, ok := series[variantID]; ok {
+			return s
+		}
+		s := &domain.PriceSeries{VariantID: variantID, Name: name}
+		series[variantID] = s
+		order = append(order, variantID)
+		return s
+	}
+	for _, variant := range product.Variants {
+		seriesFor(variant.ID, variant.Name)
+	}
+
+	for _, revision := range revisions {
+		for _, change := range revision.Changes {
+			switch change.Field {
+			case "price", "variants.price":
+				s := seriesFor(change.VariantID, change.VariantID)
+				// Prices from before history was recorded apply since creation
+				if len(s.Points) == 0 && revision.Action != domain.RevisionActionCreate {
+					if old, ok := toFloat(change.Old); ok && old > 0 {
+						s.Points = append(s.Points, domain.PricePoint{Date: product.CreatedAt, Price: old})
+					}
+				}
+				if price, ok := toFloat(change.New); ok {
+					s.Points = append(s.Points, domain.PricePoint{Date: revision.CreatedAt, Price: price})
+				}
+			case "variants":
+				// An added variant starts its series
+				added, ok := change.New.(primitive.M)
+				if !ok {
+					continue
+				}
+				name, _ := added["name"].(string)
+				s := seriesFor(change.VariantID, name)
+				if price, ok := toFloat(added["price"]); ok {
+					s.Points = append(s.Points, domain.PricePoint{Date: revision.CreatedAt, Price: price})
+				}
+			}
+		}
+	}
+
+	// Prices that never changed since history was recorded
+	if len(series[""].Points) == 0 && product.Price > 0 {
+		series[""].Points = []domain.PricePoint{{Date: product.CreatedAt, Price: product.Price}}
+	}
+	for _, variant := range product.Variants {
+		if s := series[variant.ID]; len(s.Points) == 0 {
+			s.Points = []domain.PricePoint{{Date: product.CreatedAt, Price: variant.Price}}
+		}
+	}
+
+	history := &domain.PriceHistory{ProductID: product.ID, Series: make([]domain.PriceSeries, 0, len(order))}
+	for _, variantID := range order {
+		s := series[variantID]
+		s.Points = pricePointsBetween(s.Points, from, to)
+		if len(s.Points) > 0 {
+			history.Series = append(history.Series, *s)
+		}
+	}
+
+	return history, nil
+}
+
+// getProductOrDeleted retrieves a product whether or not it is in the trash
+func (u *ProductUseCase) getProductOrDeleted(ctx context.Context, id primitive.ObjectID) (*domain.Product, error) {
+	product, err := u.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		product, err = u.productRepo.GetDeletedByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if product == nil {
+		return nil, errors.New("product not found")
+	}
+	return product, nil
+}
+
+// pricePointsBetween returns the points between from and to, starting with
+// the price in effect at from
+func pricePointsBetween(points []domain.PricePoint, from, to time.Time) []domain.PricePoint {
+	var result []domain.PricePoint
+	for _, point := range points {
+		if !to.IsZero() && point.Date.After(to) {
+			break
+		}
+		if !from.IsZero() && point.Date.Before(from) {
+			// Only the latest price before from is kept, moved to from
+			result = []domain.PricePoint{{Date: from, Price: point.Price}}
+			continue
+		}
+		result = append(result, point)
+	}
+	return result
+}
+
+// recordRevision records the changes made to a product by the actor of the
+// context. Updates that changed nothing are not recorded. The change itself
+// is already saved, so a revision that cannot be saved is logged instead of
+// failing the request.
+func recordRevision(ctx context.Context, repo domain.ProductRevisionRepository, log logger.Logger, productID primitive.ObjectID, action string, changes []domain.FieldChange) {
+	if len(changes) == 0 && (action == domain.RevisionActionUpdate || action == domain.RevisionActionStock) {
+		return
+	}
+
+	if changes == nil {
+		changes = []domain.FieldChange{}
+	}
+
+	revision := &domain.ProductRevision{
+		ProductID: productID,
+		Action:    action,
+		Changes:   changes,
+	}
+	if actor, ok := domain.ActorFromContext(ctx); ok {
+		revision.Actor = &actor
+	}
+
+	if err := repo.Create(ctx, revision); err != nil {
+		log.Error("Failed to record %s revision of product %s: %v", action, productID.Hex(), err)
+	}
+}
+
+// stockChanges returns the changes of setting the stock of a product, or of
+// one of its variants and with it the product total
+func stockChanges(product *domain.Product, variant *domain.ProductVariant, stock int) []domain.FieldChange {
+	if variant == nil {
+		return diffValue(nil, "stock", "", product.Stock, stock)
+	}
+
+	changes := diffValue(nil, "variants.stock", variant.ID, variant.Stock, stock)
+	return diffValue(changes, "stock", "", product.Stock, product.Stock-variant.Stock+stock)
+}
+
+// diffProducts returns the field changes between two versions of a product.
+// A new product is compared with an empty one.
+func diffProducts(before, after *domain.Product) []domain.FieldChange {
+	var changes []domain.FieldChange
+	changes = diffValue(changes, "sku", "", before.SKU, after.SKU)
+	changes = diffValue(changes, "barcodes", "", before.Barcodes, after.Barcodes)
+	changes = diffValue(changes, "name", "", before.Name, after.Name)
+	changes = diffValue(changes, "description", "", before.Description, after.Description)
+	changes = diffValue(changes, "price", "", before.Price, after.Price)
+	changes = diffValue(changes, "category", "", before.Category, after.Category)
+	changes = diffValue(changes, "brand", "", before.Brand, after.Brand)
+	changes = diffValue(changes, "image_url", "", before.ImageURL, after.ImageURL)
+	changes = diffValue(changes, "images", "", imageIDs(before.Images), imageIDs(after.Images))
+	changes = diffValue(changes, "stock", "", before.Stock, after.Stock)
+	changes = diffValue(changes, "specs", "", before.Specs, after.Specs)
+	changes = diffValue(changes, "is_active", "", before.IsActive, after.IsActive)
+
+	// Variants are compared by ID
+	previous := make(map[string]domain.ProductVariant, len(before.Variants))
+	for _, variant := range before.Variants {
+		previous[variant.ID] = variant
+	}
+	for _, variant := range after.Variants {
+		old, ok := previous[variant.ID]
+		if !ok {
+			changes = append(changes, domain.FieldChange{Field: "variants", VariantID: variant.ID, New: variant})
+			continue
+		}
+		delete(previous, variant.ID)
+
+		changes = diffValue(changes, "variants.sku", variant.ID, old.SKU, variant.SKU)
+		changes = diffValue(changes, "variants.barcodes", variant.ID, old.Barcodes, variant.Barcodes)
+		changes = diffValue(changes, "variants.name", variant.ID, old.Name, variant.Name)
+		changes = diffValue(changes, "variants.attributes", variant.ID, old.Attributes, variant.Attributes)
+		changes = diffValue(changes, "variants.price", variant.ID, old.Price, variant.Price)
+		changes = diffValue(changes, "variants.stock", variant.ID, old.Stock, variant.Stock)
+		changes = diffValue(changes, "variants.is_active", variant.ID, old.IsActive, variant.IsActive)
+	}
+	for _, variant := range before.Variants {
+		if _, removed := previous[variant.ID]; removed {
+			changes = append(changes, domain.FieldChange{Field: "variants", VariantID: variant.ID, Old: variant})
+		}
+	}
+
+	return changes
+}
+
+// diffValue adds a change when the old and new value differ. Empty and nil
+// lists are the same.
+func diffValue(changes []domain.FieldChange, field, variantID string, old, new interface{}) []domain.FieldChange {
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(new)
+	if isEmptyList(oldValue) && isEmptyList(newValue) {
+		return changes
+	}
+	if reflect.DeepEqual(old, new) {
+		return changes
+	}
+	return append(changes, domain.FieldChange{Field: field, VariantID: variantID, Old: old, New: new})
+}
+
+// isEmptyList reports whether a value is an empty slice or map
+func isEmptyList(value reflect.Value) bool {
+	return (value.Kind() == reflect.Slice || value.Kind() == reflect.Map) && value.Len() == 0
+}
+
+// imageIDs returns the IDs of product images
+func imageIDs(images []domain.ProductImage) []string {
+	ids := make([]string, 0, len(images))
+	for _, image := range images {
+		ids = append(ids, image.ID)
+	}
+	return ids
+}
+
+// cloneProduct returns a copy of a product that shares no lists with it, so
+// the original version can be compared after the product is changed
+func cloneProduct(product *domain.Product) *domain.Product {
+	clone := *product
+	clone.Barcodes = append([]domain.ProductBarcode(nil), product.Barcodes...)
+	clone.Images = append([]domain.ProductImage(nil), product.Images...)
+	clone.Specs = append([]domain.ProductSpec(nil), product.Specs...)
+	clone.Variants = make([]domain.ProductVariant, len(product.Variants))
+	for i, variant := range product.Variants {
+		variant.Barcodes = append([]domain.ProductBarcode(nil), variant.Barcodes...)
+		attributes := make(map[string]string, len(variant.Attributes))
+		for name, value := range variant.Attributes {
+			attributes[name] = value
+		}
+		variant.Attributes = attributes
+		clone.Variants[i] = variant
+	}
+	return &clone
+}
+
+// toFloat converts a number decoded from BSON to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case float64:
+		return number, true
+	case int32:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case int:
+		return float64(number), true
+	}
+	return 0, false
+}
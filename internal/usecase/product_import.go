@@ -0,0 +1,577 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// importSpecPrefix starts the name of a column holding a spec value
+const importSpecPrefix = "spec."
+
+// importColumns are the columns an import file can have besides spec columns.
+// The id column of exports is accepted and ignored, rows are matched by SKU
+// or name.
+var importColumns = map[string]bool{
+	"id":          true,
+	"sku":         true,
+	"name":        true,
+	"description": true,
+	"price":       true,
+	"category":    true,
+	"brand":       true,
+	"stock":       true,
+	"is_active":   true,
+	"barcodes":    true,
+	"image_url":   true,
+	"image_urls":  true,
+	"variants":    true,
+}
+
+// InvalidImportError is returned when an import file cannot be read as a whole
+type InvalidImportError struct {
+	Reason string
+}
+
+func (e *InvalidImportError) Error() string {
+	return "invalid import file: " + e.Reason
+}
+
+// importColumn is a column of an import file, spec columns carry their attribute
+type importColumn struct {
+	name string
+	spec *domain.SpecAttribute
+}
+
+// importRecord holds the parsed cells of an import row, nil and empty values
+// are cells that were left empty
+type importRecord struct {
+	sku         string
+	name        string
+	description string
+	category    string
+	brand       string
+	imageURL    string
+	price       *float64
+	stock       *int
+	isActive    *bool
+	barcodes    []domain.ProductBarcode
+	imageURLs   []string
+	specs       []domain.ProductSpec
+	variants    []domain.ProductVariantRequest // A JSON array like in exports, [] removes the variants
+}
+
+// ImportProducts creates and updates products from the rows of a CSV or XLSX
+// file whose first row names the columns. A row updates the product with its
+// SKU, or with its name when the row has no SKU, and creates a product when
+// there is none. Empty cells keep the current values of updated products.
+// Rows with errors are skipped and a dry run only validates the rows.
+func (u *ProductUseCase) ImportProducts(ctx context.Context, table [][]string, dryRun bool) (*domain.ProductImportResult, error) {
+	if len(table) == 0 {
+		return nil, &InvalidImportError{Reason: "the file is empty"}
+	}
+
+	columns, err := u.parseImportHeader(ctx, table[0])
+	if err != nil {
+		return nil, err
+	}
+
+	rows := 0
+	for _, record := range table[1:] {
+		if !isBlankRecord(record) {
+			rows++
+		}
+	}
+	if rows > domain.MaxImportRows {
+		return nil, &InvalidImportError{Reason: fmt.Sprintf("more than %d rows", domain.MaxImportRows)}
+	}
+
+	result := &domain.ProductImportResult{DryRun: dryRun, Rows: []domain.ProductImportRow{}}
+	seen := make(map[string]int)
+	for i, record := range table[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+
+		row, err := u.importRow(ctx, columns, record, i+2, seen, dryRun)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Total++
+		switch row.Action {
+		case domain.ImportActionCreate:
+			result.Created++
+		case domain.ImportActionUpdate:
+			result.Updated++
+		case domain.ImportActionUnchanged:
+			result.Unchanged++
+		default:
+			result.Failed++
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	return result, nil
+}
+
+// importRow validates one row and, unless it is a dry run, saves it. Row
+// errors are reported on the row, the returned error is a database failure.
+// seen maps the SKUs, barcodes and names of earlier rows to their row number.
+func (u *ProductUseCase) importRow(ctx context.Context, columns []importColumn, cells []string, rowNumber int, seen map[string]int, dryRun bool) (domain.ProductImportRow, error) {
+	record, errs := parseImportRecord(columns, cells)
+	row := domain.ProductImportRow{Row: rowNumber, SKU: record.sku, Name: record.name}
+
+	if record.sku == "" && record.name == "" {
+		errs = append(errs, "sku or name is required")
+	}
+
+	// Rows later in the file would otherwise silently overwrite earlier ones
+	var keys []string
+	if record.sku != "" {
+		keys = append(keys, "SKU "+record.sku)
+	} else if record.name != "" {
+		keys = append(keys, "name "+record.name)
+	}
+	for _, barcode := range record.barcodes {
+		keys = append(keys, "barcode "+canonicalCode(barcode.Code))
+	}
+	for _, variant := range record.variants {
+		keys = append(keys, "SKU "+variant.SKU)
+		for _, barcode := range variant.Barcodes {
+			keys = append(keys, "barcode "+canonicalCode(barcode.Code))
+		}
+	}
+	for _, key := range keys {
+		if first, ok := seen[key]; ok {
+			errs = append(errs, fmt.Sprintf("%s is also in row %d", key, first))
+		} else {
+			seen[key] = rowNumber
+		}
+	}
+
+	if len(errs) > 0 {
+		row.Errors = errs
+		return row, nil
+	}
+
+	existing, reason, err := u.findImportProduct(ctx, record)
+	if err != nil {
+		return row, err
+	}
+	if reason != "" {
+		row.Errors = []string{reason}
+		return row, nil
+	}
+
+	if existing == nil {
+		return u.importCreate(ctx, row, record, dryRun)
+	}
+	return u.importUpdate(ctx, row, existing, record, dryRun)
+}
+
+// importCreate creates a product from an import row
+func (u *ProductUseCase) importCreate(ctx context.Context, row domain.ProductImportRow, record importRecord, dryRun bool) (domain.ProductImportRow, error) {
+	if record.name == "" {
+		row.Errors = append(row.Errors, "name is required")
+	}
+	if record.category == "" {
+		row.Errors = append(row.Errors, "category is required")
+	}
+	if len(record.variants) > 0 && (record.price != nil || record.stock != nil) {
+		row.Errors = append(row.Errors, "price and stock of a product with variants are set on its variants")
+	} else if record.price == nil && len(record.variants) == 0 {
+		row.Errors = append(row.Errors, "price is required")
+	}
+	if len(row.Errors) > 0 {
+		return row, nil
+	}
+
+	req := domain.CreateProductRequest{
+		SKU:         record.sku,
+		Barcodes:    record.barcodes,
+		Name:        record.name,
+		Description: record.description,
+		Category:    record.category,
+		Brand:       record.brand,
+		ImageURL:    record.imageURL,
+		ImageURLs:   record.imageURLs,
+		Variants:    record.variants,
+		Specs:       record.specs,
+	}
+	if record.price != nil {
+		req.Price = *record.price
+	}
+	if record.stock != nil {
+		req.Stock = *record.stock
+	}
+	// Variant IDs of an export from another store do not exist here
+	for i := range req.Variants {
+		req.Variants[i].ID = ""
+	}
+
+	product, err := u.newProduct(ctx, req, nil)
+	if err != nil {
+		row.Errors = []string{err.Error()}
+		return row, nil
+	}
+	if record.isActive != nil {
+		product.IsActive = *record.isActive
+	}
+
+	row.Action = domain.ImportActionCreate
+	if dryRun {
+		return row, nil
+	}
+	if err := u.createProduct(ctx, product); err != nil {
+		return row, err
+	}
+	row.ProductID = &product.ID
+	return row, nil
+}
+
+// importUpdate updates an existing product from an import row
+func (u *ProductUseCase) importUpdate(ctx context.Context, row domain.ProductImportRow, product *domain.Product, record importRecord, dryRun bool) (domain.ProductImportRow, error) {
+	row.ProductID = &product.ID
+	hasVariants := len(record.variants) > 0 || (record.variants == nil && len(product.Variants) > 0)
+	if hasVariants && (record.price != nil || record.stock != nil) {
+		row.Errors = []string{"price and stock of a product with variants are set on its variants"}
+		return row, nil
+	}
+
+	before := cloneProduct(product)
+	req := domain.UpdateProductRequest{
+		SKU:         record.sku,
+		Barcodes:    record.barcodes,
+		Name:        record.name,
+		Description: record.description,
+		Category:    record.category,
+		Brand:       record.brand,
+		ImageURL:    record.imageURL,
+		ImageURLs:   record.imageURLs,
+		Stock:       record.stock,
+		IsActive:    record.isActive,
+		Variants:    record.variants,
+	}
+	if record.price != nil {
+		req.Price = *record.price
+	}
+	if len(record.specs) > 0 {
+		req.Specs = mergeSpecs(product.Specs, record.specs)
+	}
+	// Exports list uploaded images with the URL images, they stay as they are
+	if record.imageURLs != nil {
+		req.ImageURLs = withoutUploadedImages(product, record.imageURLs)
+	}
+	// Replacing the images with the same URLs would only give them new IDs
+	if sameStrings(urlImages(product), req.ImageURLs) {
+		req.ImageURLs = nil
+	}
+
+	if err := u.applyUpdate(ctx, product, req); err != nil {
+		row.Errors = []string{err.Error()}
+		return row, nil
+	}
+
+	if len(diffProducts(before, product)) == 0 {
+		row.Action = domain.ImportActionUnchanged
+		return row, nil
+	}
+
+	if dryRun {
+		row.Action = domain.ImportActionUpdate
+		return row, nil
+	}
+	if err := u.updateProduct(ctx, before, product); err != nil {
+		if err.Error() == "version mismatch" {
+			row.Errors = []string{"product was changed during the import"}
+			return row, nil
+		}
+		return row, err
+	}
+	row.Action = domain.ImportActionUpdate
+	return row, nil
+}
+
+// findImportProduct finds the product an import row updates, by SKU or else
+// by name. It returns nil when the row creates a product, and a reason when
+// the row cannot be matched to a single product.
+func (u *ProductUseCase) findImportProduct(ctx context.Context, record importRecord) (*domain.Product, string, error) {
+	if record.sku != "" {
+		products, err := u.productRepo.ListByCodes(ctx, []string{record.sku})
+		if err != nil {
+			return nil, "", err
+		}
+		for _, product := range products {
+			if product.SKU != record.sku {
+				continue
+			}
+			if product.DeletedAt != nil {
+				return nil, "SKU belongs to a product in the trash", nil
+			}
+			return product, "", nil
+		}
+		return nil, "", nil
+	}
+
+	products, err := u.productRepo.ListByName(ctx, record.name)
+	if err != nil {
+		return nil, "", err
+	}
+	switch len(products) {
+	case 0:
+		return nil, "", nil
+	case 1:
+		return products[0], "", nil
+	default:
+		return nil, "name matches more than one product, add a SKU", nil
+	}
+}
+
+// parseImportHeader maps the header row to columns. Column names are not
+// case sensitive and spec columns are named spec.<key>.
+func (u *ProductUseCase) parseImportHeader(ctx context.Context, header []string) ([]importColumn, error) {
+	columns := make([]importColumn, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, cell := range header {
+		name := strings.ToLower(strings.Join(strings.Fields(cell), "_"))
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			return nil, &InvalidImportError{Reason: fmt.Sprintf("column %q appears more than once", cell)}
+		}
+		seen[name] = true
+
+		if key, ok := strings.CutPrefix(name, importSpecPrefix); ok {
+			attribute, err := u.specRepo.GetByKey(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			if attribute == nil {
+				return nil, &InvalidImportError{Reason: fmt.Sprintf("unknown spec attribute %q", key)}
+			}
+			columns[i] = importColumn{name: name, spec: attribute}
+			continue
+		}
+		if !importColumns[name] {
+			return nil, &InvalidImportError{Reason: fmt.Sprintf("unknown column %q", cell)}
+		}
+		columns[i] = importColumn{name: name}
+	}
+
+	if !seen["sku"] && !seen["name"] {
+		return nil, &InvalidImportError{Reason: "a sku or name column is required"}
+	}
+	return columns, nil
+}
+
+// parseImportRecord parses the cells of a row and returns the errors of the
+// cells that cannot be parsed
+func parseImportRecord(columns []importColumn, cells []string) (importRecord, []string) {
+	var record importRecord
+	var errs []string
+	for i, cell := range cells {
+		value := strings.TrimSpace(cell)
+		if value == "" {
+			continue
+		}
+		if i >= len(columns) || columns[i].name == "" {
+			errs = append(errs, fmt.Sprintf("value in column %d has no header", i+1))
+			continue
+		}
+
+		column := columns[i]
+		if column.spec != nil {
+			specValue, err := parseSpecValue(column.spec, value)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			record.specs = append(record.specs, domain.ProductSpec{Key: column.spec.Key, Value: specValue})
+			continue
+		}
+
+		switch column.name {
+		case "sku":
+			record.sku = value
+		case "name":
+			record.name = value
+		case "description":
+			record.description = value
+		case "category":
+			record.category = value
+		case "brand":
+			record.brand = value
+		case "image_url":
+			record.imageURL = value
+		case "image_urls":
+			record.imageURLs = splitImportList(value)
+		case "barcodes":
+			for _, code := range splitImportList(value) {
+				record.barcodes = append(record.barcodes, domain.ProductBarcode{Code: code})
+			}
+		case "price":
+			price, err := parseImportNumber(value)
+			if err != nil {
+				errs = append(errs, "invalid price")
+			} else if price <= 0 {
+				errs = append(errs, "price must be greater than 0")
+			} else {
+				record.price = &price
+			}
+		case "stock":
+			stock, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, "invalid stock")
+			} else if stock < 0 {
+				errs = append(errs, "stock cannot be negative")
+			} else {
+				record.stock = &stock
+			}
+		case "is_active":
+			isActive, err := parseImportBool(value)
+			if err != nil {
+				errs = append(errs, "invalid is_active value")
+			} else {
+				record.isActive = &isActive
+			}
+		case "variants":
+			variants := []domain.ProductVariantRequest{}
+			if err := json.Unmarshal([]byte(value), &variants); err != nil {
+				errs = append(errs, "invalid variants, expected a JSON array")
+			} else {
+				record.variants = variants
+			}
+		}
+	}
+	return record, errs
+}
+
+// mergeSpecs returns the current specs with the given specs added or replacing
+// the specs with the same key
+func mergeSpecs(current, specs []domain.ProductSpec) []domain.ProductSpec {
+	merged := make([]domain.ProductSpec, 0, len(current)+len(specs))
+	replaced := make(map[string]bool, len(specs))
+	for _, spec := range current {
+		for _, update := range specs {
+			if update.Key == spec.Key {
+				spec = update
+				replaced[spec.Key] = true
+				break
+			}
+		}
+		merged = append(merged, spec)
+	}
+	for _, spec := range specs {
+		if !replaced[spec.Key] {
+			merged = append(merged, spec)
+		}
+	}
+	return merged
+}
+
+// urlImages returns the URLs of the images of a product that are not uploaded files
+func urlImages(product *domain.Product) []string {
+	var urls []string
+	for _, image := range product.Images {
+		if image.IsURL {
+			urls = append(urls, image.URL)
+		}
+	}
+	return urls
+}
+
+// withoutUploadedImages returns the URLs that are not uploaded images of the product
+func withoutUploadedImages(product *domain.Product, urls []string) []string {
+	result := make([]string, 0, len(urls))
+	for _, url := range urls {
+		uploaded := false
+		for _, image := range product.Images {
+			if !image.IsURL && image.URL == url {
+				uploaded = true
+				break
+			}
+		}
+		if !uploaded {
+			result = append(result, url)
+		}
+	}
+	return result
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseImportNumber parses a number that may use a decimal comma, or commas
+// and spaces as thousands separators. Commas that are each followed by
+// exactly three digits, as in 12,500 or 1,250,000, separate thousands; a
+// single other comma, as in 12,5, is a decimal comma.
+func parseImportNumber(value string) (float64, error) {
+	value = strings.Join(strings.Fields(value), "")
+	if strings.Contains(value, ".") || isThousandsGrouped(value) {
+		value = strings.ReplaceAll(value, ",", "")
+	} else if strings.Count(value, ",") == 1 {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// isThousandsGrouped reports whether a number has commas between groups of
+// three digits, such as 1,250,000. A leading zero, as in 0,125, is a decimal.
+func isThousandsGrouped(value string) bool {
+	groups := strings.Split(strings.TrimPrefix(value, "-"), ",")
+	if len(groups) < 2 || len(groups[0]) == 0 || len(groups[0]) > 3 || groups[0][0] == '0' {
+		return false
+	}
+	for i, group := range groups {
+		if !utils.IsDigits(group) || (i > 0 && len(group) != 3) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseImportBool parses true and false as well as yes and no
+func parseImportBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// splitImportList splits a comma separated cell, dropping empty items
+func splitImportList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func isBlankRecord(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
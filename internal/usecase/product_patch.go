@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InvalidPatchError is returned when a patch cannot be applied to a product
+type InvalidPatchError struct {
+	Reason string
+}
+
+func (e *InvalidPatchError) Error() string {
+	return "invalid patch: " + e.Reason
+}
+
+// PatchProduct applies a JSON Merge Patch or JSON Patch to the fields of a
+// product in domain.ProductPatchDocument. Fields the patch leaves as they
+// are keep their value, and nothing is saved when no field changes. The
+// product must be at the version the client last read.
+func (u *ProductUseCase) PatchProduct(ctx context.Context, id primitive.ObjectID, version int64, patchType string, patch []byte) (*domain.Product, error) {
+	product, err := u.getProductAtVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	before := cloneProduct(product)
+
+	current := productPatchDocument(product)
+	data, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+
+	switch patchType {
+	case domain.PatchTypeMerge:
+		data, err = utils.MergePatch(data, patch)
+	case domain.PatchTypeJSON:
+		data, err = utils.ApplyJSONPatch(data, patch)
+	default:
+		return nil, errors.New("unsupported patch type")
+	}
+	if errors.Is(err, utils.ErrPatchTestFailed) {
+		return nil, errors.New("patch test failed")
+	}
+	if err != nil {
+		return nil, &InvalidPatchError{Reason: err.Error()}
+	}
+
+	var patched domain.ProductPatchDocument
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, &InvalidPatchError{Reason: typeErr.Field + " must be " + jsonTypeName(typeErr.Type)}
+		}
+		return nil, &InvalidPatchError{Reason: strings.TrimPrefix(err.Error(), "json: ")}
+	}
+
+	if err := u.applyPatchDocument(ctx, product, current, patched); err != nil {
+		return nil, err
+	}
+
+	if len(diffProducts(before, product)) == 0 {
+		return product, nil
+	}
+	if err := u.updateProduct(ctx, before, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// productPatchDocument returns the fields of a product that patches change
+func productPatchDocument(product *domain.Product) domain.ProductPatchDocument {
+	variants := make([]domain.ProductVariantRequest, len(product.Variants))
+	for i, variant := range product.Variants {
+		isActive := variant.IsActive
+		variants[i] = domain.ProductVariantRequest{
+			ID:         variant.ID,
+			SKU:        variant.SKU,
+			Barcodes:   variant.Barcodes,
+			Name:       variant.Name,
+			Attributes: variant.Attributes,
+			Price:      variant.Price,
+			Stock:      variant.Stock,
+			IsActive:   &isActive,
+		}
+	}
+
+	imageURLs := urlImages(product)
+	if imageURLs == nil {
+		imageURLs = []string{}
+	}
+	barcodes := product.Barcodes
+	if barcodes == nil {
+		barcodes = []domain.ProductBarcode{}
+	}
+	specs := product.Specs
+	if specs == nil {
+		specs = []domain.ProductSpec{}
+	}
+
+	return domain.ProductPatchDocument{
+		SKU:         product.SKU,
+		Barcodes:    barcodes,
+		Name:        product.Name,
+		Description: product.Description,
+		Price:       product.Price,
+		Category:    product.Category,
+		Brand:       product.Brand,
+		ImageURLs:   imageURLs,
+		Stock:       product.Stock,
+		IsActive:    product.IsActive,
+		Variants:    variants,
+		Specs:       specs,
+	}
+}
+
+// applyPatchDocument sets the fields of a product from a patched document.
+// A removed list is treated as empty.
+func (u *ProductUseCase) applyPatchDocument(ctx context.Context, product *domain.Product, current, patched domain.ProductPatchDocument) error {
+	if strings.TrimSpace(patched.Name) == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(patched.Category) == "" {
+		return errors.New("category is required")
+	}
+
+	product.Name = patched.Name
+	product.Description = patched.Description
+	product.Category = patched.Category
+	product.Brand = patched.Brand
+	product.IsActive = patched.IsActive
+
+	if len(patched.Variants) > 0 {
+		if patched.Price != current.Price || patched.Stock != current.Stock {
+			return errors.New("price and stock of a product with variants are set on its variants")
+		}
+	} else {
+		if patched.Price <= 0 {
+			return errors.New("price must be greater than 0")
+		}
+		if patched.Stock < 0 {
+			return errors.New("stock cannot be negative")
+		}
+		product.Price = patched.Price
+		product.Stock = patched.Stock
+	}
+
+	if !sameStrings(current.ImageURLs, patched.ImageURLs) {
+		replaceURLImages(product, patched.ImageURLs)
+	}
+
+	variants := patched.Variants
+	if variants == nil {
+		variants = []domain.ProductVariantRequest{}
+	}
+	if err := applyVariants(product, variants); err != nil {
+		return err
+	}
+
+	barcodes := patched.Barcodes
+	if barcodes == nil {
+		barcodes = []domain.ProductBarcode{}
+	}
+	product.SKU = strings.TrimSpace(patched.SKU)
+	if err := u.applyCodes(ctx, product, product.SKU, barcodes); err != nil {
+		return err
+	}
+
+	specs := patched.Specs
+	if specs == nil {
+		specs = []domain.ProductSpec{}
+	}
+	return u.applySpecs(ctx, product, specs)
+}
+
+// replaceURLImages replaces the URL images of a product and keeps its
+// uploaded images. Images whose URL stays keep their ID, and the first image
+// becomes primary when the primary image was removed.
+func replaceURLImages(product *domain.Product, urls []string) {
+	existing := make(map[string]domain.ProductImage)
+	images := make([]domain.ProductImage, 0, len(product.Images)+len(urls))
+	for _, image := range product.Images {
+		if image.IsURL {
+			existing[image.URL] = image
+		} else {
+			images = append(images, image)
+		}
+	}
+
+	for _, url := range urls {
+		if url = strings.TrimSpace(url); url == "" {
+			continue
+		}
+		image, ok := existing[url]
+		if !ok {
+			image = domain.ProductImage{
+				ID:        uuid.New().String(),
+				URL:       url,
+				IsURL:     true,
+				CreatedAt: time.Now(),
+			}
+		}
+		delete(existing, url)
+		images = append(images, image)
+	}
+
+	hasPrimary := false
+	hasLegacyImage := false
+	for _, image := range images {
+		hasPrimary = hasPrimary || image.IsPrimary
+		hasLegacyImage = hasLegacyImage || image.URL == product.ImageURL
+	}
+	if !hasPrimary && len(images) > 0 {
+		images[0].IsPrimary = true
+	}
+	if !hasLegacyImage {
+		product.ImageURL = ""
+	}
+
+	product.Images = images
+}
+
+// jsonTypeName names the JSON type a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int64:
+		return "a whole number"
+	case reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// descriptionSnippetLength is the approximate length of the description
+// snippet shown around the first match
+const descriptionSnippetLength = 160
+
+// searchPattern returns a case-insensitive pattern for the search terms. Full-text
+// search matches words by their stem ("mowers" finds "mower"), so stems are
+// matched at the start of a word; partial search matches the terms anywhere.
+func searchPattern(terms []string, partial bool) *regexp.Regexp {
+	alternatives := make([]string, 0, len(terms))
+	for _, term := range terms {
+		if !partial {
+			term = searchStem(term)
+		}
+		alternatives = append(alternatives, regexp.QuoteMeta(term))
+	}
+
+	// Prefer the longest match when terms overlap
+	sort.Slice(alternatives, func(i, j int) bool {
+		return len(alternatives[i]) > len(alternatives[j])
+	})
+
+	if partial {
+		return regexp.MustCompile(`(?i)(?:` + strings.Join(alternatives, "|") + `)`)
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)\w*`)
+}
+
+// searchStem strips common English suffixes from a search term
+func searchStem(term string) string {
+	term = strings.ToLower(term)
+	for _, suffix := range []string{"ing", "es", "ed", "s"} {
+		if stem := strings.TrimSuffix(term, suffix); stem != term && len(stem) >= 3 {
+			return stem
+		}
+	}
+	return term
+}
+
+// highlightProduct returns the name, brand and description of a product that
+// match the pattern, with the matches wrapped in <mark> and the rest HTML-escaped
+func highlightProduct(product *domain.Product, pattern *regexp.Regexp) map[string]string {
+	highlights := make(map[string]string)
+	if snippet, ok := highlight(product.Name, pattern, 0); ok {
+		highlights["name"] = snippet
+	}
+	if snippet, ok := highlight(product.Brand, pattern, 0); ok {
+		highlights["brand"] = snippet
+	}
+	if snippet, ok := highlight(product.Description, pattern, descriptionSnippetLength); ok {
+		highlights["description"] = snippet
+	}
+
+	if len(highlights) == 0 {
+		return nil
+	}
+	return highlights
+}
+
+// highlight marks the matches of the pattern in text. Texts longer than
+// maxLength are cut to a snippet around the first match; 0 keeps the whole text.
+func highlight(text string, pattern *regexp.Regexp, maxLength int) (string, bool) {
+	matches := pattern.FindAllStringIndex(text, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	start, end := 0, len(text)
+	if maxLength > 0 && len(text) > maxLength {
+		start = matches[0][0] - maxLength/3
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxLength
+		if end > len(text) {
+			end = len(text)
+		}
+
+		// Cut at spaces so no word, or character, is split
+		if i := strings.LastIndexByte(text[:start], ' '); i >= 0 {
+			start = i + 1
+		} else {
+			start = 0
+		}
+		if i := strings.IndexByte(text[end:], ' '); i >= 0 {
+			end += i
+		} else {
+			end = len(text)
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, match := range matches {
+		if match[0] < start || match[1] > end {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:match[0]]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[match[0]:match[1]]))
+		b.WriteString("</mark>")
+		pos = match[1]
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String(), true
+}
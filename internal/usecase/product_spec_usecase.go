@@ -0,0 +1,333 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// specKeyPattern is the format of spec attribute keys, which are used as
+// query parameters
+var specKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,39}$`)
+
+// reservedSpecKeys are product list query parameters spec attributes cannot use
+var reservedSpecKeys = map[string]bool{
+	"category":  true,
+	"brand":     true,
+	"search":    true,
+	"min_price": true,
+	"max_price": true,
+	"is_active": true,
+	"page":      true,
+	"limit":     true,
+	"sort":      true,
+	"order":     true,
+	"cursor":    true,
+	"facets":    true,
+	"format":    true,
+	"code":      true,
+}
+
+// InvalidSpecError is returned when a spec value or spec filter does not
+// match its attribute
+type InvalidSpecError struct {
+	Key    string
+	Reason string
+}
+
+func (e *InvalidSpecError) Error() string {
+	return "invalid spec " + e.Key + ": " + e.Reason
+}
+
+// CreateSpecAttribute creates a new spec attribute
+func (u *ProductUseCase) CreateSpecAttribute(ctx context.Context, req domain.CreateSpecAttributeRequest) (*domain.SpecAttribute, error) {
+	key := strings.TrimSpace(req.Key)
+	if !specKeyPattern.MatchString(key) || reservedSpecKeys[key] ||
+		strings.HasSuffix(key, "_min") || strings.HasSuffix(key, "_max") {
+		return nil, errors.New("invalid spec attribute key")
+	}
+
+	existing, err := u.specRepo.GetByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("spec attribute already exists")
+	}
+
+	attribute := &domain.SpecAttribute{
+		Key:  key,
+		Name: strings.TrimSpace(req.Name),
+		Type: req.Type,
+	}
+	switch req.Type {
+	case domain.SpecTypeNumber:
+		attribute.Unit = strings.TrimSpace(req.Unit)
+	case domain.SpecTypeEnum:
+		if attribute.Values, err = normalizeSpecValues(req.Values); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := u.specRepo.Create(ctx, attribute); err != nil {
+		return nil, err
+	}
+	return attribute, nil
+}
+
+// GetSpecAttributes retrieves all spec attributes
+func (u *ProductUseCase) GetSpecAttributes(ctx context.Context) ([]*domain.SpecAttribute, error) {
+	return u.specRepo.List(ctx)
+}
+
+// UpdateSpecAttribute updates the name or enum values of a spec attribute.
+// Enum values that products still use cannot be removed.
+func (u *ProductUseCase) UpdateSpecAttribute(ctx context.Context, id primitive.ObjectID, req domain.UpdateSpecAttributeRequest) (*domain.SpecAttribute, error) {
+	attribute, err := u.getSpecAttribute(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		attribute.Name = name
+	}
+	if req.Values != nil {
+		if attribute.Type != domain.SpecTypeEnum {
+			return nil, errors.New("only enum attributes have values")
+		}
+		values, err := normalizeSpecValues(req.Values)
+		if err != nil {
+			return nil, err
+		}
+
+		var removed []interface{}
+		for _, value := range attribute.Values {
+			if !containsString(values, value) {
+				removed = append(removed, value)
+			}
+		}
+		if len(removed) > 0 {
+			count, err := u.productRepo.CountBySpec(ctx, attribute.Key, removed)
+			if err != nil {
+				return nil, err
+			}
+			if count > 0 {
+				return nil, errors.New("spec value is in use")
+			}
+		}
+		attribute.Values = values
+	}
+
+	if err := u.specRepo.Update(ctx, attribute); err != nil {
+		return nil, err
+	}
+	return attribute, nil
+}
+
+// DeleteSpecAttribute deletes a spec attribute that no product uses
+func (u *ProductUseCase) DeleteSpecAttribute(ctx context.Context, id primitive.ObjectID) error {
+	attribute, err := u.getSpecAttribute(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := u.productRepo.CountBySpec(ctx, attribute.Key, nil)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("spec attribute is in use")
+	}
+
+	return u.specRepo.Delete(ctx, attribute.ID)
+}
+
+// getSpecAttribute retrieves a spec attribute by ID
+func (u *ProductUseCase) getSpecAttribute(ctx context.Context, id primitive.ObjectID) (*domain.SpecAttribute, error) {
+	attribute, err := u.specRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if attribute == nil {
+		return nil, errors.New("spec attribute not found")
+	}
+	return attribute, nil
+}
+
+// ParseSpecFilters builds spec filters from product list query parameters.
+// Every attribute can be filtered by value with its key, enum and number
+// values separated by commas, and number attributes by range with the
+// <key>_min and <key>_max parameters. Other parameters are ignored.
+func (u *ProductUseCase) ParseSpecFilters(ctx context.Context, params map[string][]string) ([]domain.SpecFilter, error) {
+	if len(params) == 0 {
+		return nil, nil
+	}
+
+	attributes, err := u.specRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var filters []domain.SpecFilter
+	for _, attribute := range attributes {
+		filter := domain.SpecFilter{Key: attribute.Key}
+
+		if value := firstParam(params, attribute.Key); value != "" {
+			for _, item := range strings.Split(value, ",") {
+				parsed, err := parseSpecValue(attribute, strings.TrimSpace(item))
+				if err != nil {
+					return nil, err
+				}
+				filter.Values = append(filter.Values, parsed)
+			}
+		}
+
+		if attribute.Type == domain.SpecTypeNumber {
+			if filter.Min, err = parseSpecBound(attribute.Key, firstParam(params, attribute.Key+"_min")); err != nil {
+				return nil, err
+			}
+			if filter.Max, err = parseSpecBound(attribute.Key, firstParam(params, attribute.Key+"_max")); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(filter.Values) > 0 || filter.Min != nil || filter.Max != nil {
+			filters = append(filters, filter)
+		}
+	}
+
+	return filters, nil
+}
+
+// applySpecs replaces the specs of a product with the requested ones. Nil
+// keeps the current specs, an empty list removes them.
+func (u *ProductUseCase) applySpecs(ctx context.Context, product *domain.Product, specs []domain.ProductSpec) error {
+	if specs == nil {
+		return nil
+	}
+	if len(specs) == 0 {
+		product.Specs = []domain.ProductSpec{}
+		return nil
+	}
+
+	attributes, err := u.specRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]*domain.SpecAttribute, len(attributes))
+	for _, attribute := range attributes {
+		byKey[attribute.Key] = attribute
+	}
+
+	result := make([]domain.ProductSpec, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		attribute, ok := byKey[spec.Key]
+		if !ok {
+			return &InvalidSpecError{Key: spec.Key, Reason: "unknown attribute"}
+		}
+		if seen[spec.Key] {
+			return &InvalidSpecError{Key: spec.Key, Reason: "given more than once"}
+		}
+		seen[spec.Key] = true
+
+		value, err := normalizeSpecValue(attribute, spec.Value)
+		if err != nil {
+			return err
+		}
+		result = append(result, domain.ProductSpec{Key: attribute.Key, Value: value, Unit: attribute.Unit})
+	}
+
+	product.Specs = result
+	return nil
+}
+
+// normalizeSpecValue checks that a JSON spec value matches the attribute
+// type and returns it as float64, string or bool
+func normalizeSpecValue(attribute *domain.SpecAttribute, value interface{}) (interface{}, error) {
+	switch attribute.Type {
+	case domain.SpecTypeNumber:
+		number, ok := value.(float64)
+		if !ok || math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, &InvalidSpecError{Key: attribute.Key, Reason: "value must be a number"}
+		}
+		return number, nil
+	case domain.SpecTypeBoolean:
+		boolean, ok := value.(bool)
+		if !ok {
+			return nil, &InvalidSpecError{Key: attribute.Key, Reason: "value must be true or false"}
+		}
+		return boolean, nil
+	default:
+		text, ok := value.(string)
+		if !ok || !containsString(attribute.Values, text) {
+			return nil, &InvalidSpecError{Key: attribute.Key, Reason: "value must be one of " + strings.Join(attribute.Values, ", ")}
+		}
+		return text, nil
+	}
+}
+
+// parseSpecValue parses a spec value from a query parameter
+func parseSpecValue(attribute *domain.SpecAttribute, value string) (interface{}, error) {
+	switch attribute.Type {
+	case domain.SpecTypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, &InvalidSpecError{Key: attribute.Key, Reason: "value must be a number"}
+		}
+		return normalizeSpecValue(attribute, number)
+	case domain.SpecTypeBoolean:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, &InvalidSpecError{Key: attribute.Key, Reason: "value must be true or false"}
+		}
+		return boolean, nil
+	default:
+		return normalizeSpecValue(attribute, value)
+	}
+}
+
+// parseSpecBound parses the _min or _max query parameter of a number attribute
+func parseSpecBound(key, value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return nil, &InvalidSpecError{Key: key, Reason: "range must be a number"}
+	}
+	return &number, nil
+}
+
+// normalizeSpecValues trims enum values and checks that they are unique
+func normalizeSpecValues(values []string) ([]string, error) {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || strings.Contains(value, ",") {
+			return nil, errors.New("enum values cannot be empty or contain commas")
+		}
+		if containsString(result, value) {
+			return nil, errors.New("duplicate enum value")
+		}
+		result = append(result, value)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("enum attributes require values")
+	}
+	return result, nil
+}
+
+// firstParam returns the first value of a query parameter
+func firstParam(params map[string][]string, key string) string {
+	if values := params[key]; len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
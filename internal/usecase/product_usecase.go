@@ -2,8 +2,13 @@ package usecase
 
 import (
 	"agricultural-equipment-store/internal/domain"
+	"agricultural-equipment-store/internal/infrastructure/logger"
+	"agricultural-equipment-store/internal/utils"
 	"context"
 	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,13 +17,23 @@ import (
 
 // ProductUseCase handles product related business logic
 type ProductUseCase struct {
-	productRepo domain.ProductRepository
+	productRepo       domain.ProductRepository
+	specRepo          domain.SpecAttributeRepository
+	revisionRepo      domain.ProductRevisionRepository
+	compatibilityRepo domain.CompatibilityRepository
+	uploadConfig      *utils.UploadConfig
+	logger            logger.Logger
 }
 
 // NewProductUseCase creates a new product use case
-func NewProductUseCase(productRepo domain.ProductRepository) *ProductUseCase {
+func NewProductUseCase(productRepo domain.ProductRepository, specRepo domain.SpecAttributeRepository, revisionRepo domain.ProductRevisionRepository, compatibilityRepo domain.CompatibilityRepository, logger logger.Logger) *ProductUseCase {
 	return &ProductUseCase{
-		productRepo: productRepo,
+		productRepo:       productRepo,
+		specRepo:          specRepo,
+		revisionRepo:      revisionRepo,
+		compatibilityRepo: compatibilityRepo,
+		uploadConfig:      utils.NewUploadConfig(),
+		logger:            logger,
 	}
 }
 
@@ -35,6 +50,16 @@ func (u *ProductUseCase) CreateProduct(ctx context.Context, req domain.CreatePro
 		IsActive:    true,
 	}
 
+	if err := applyVariants(product, req.Variants); err != nil {
+		return nil, err
+	}
+	if err := u.applyCodes(ctx, product, req.SKU, req.Barcodes); err != nil {
+		return nil, err
+	}
+	if err := u.applySpecs(ctx, product, req.Specs); err != nil {
+		return nil, err
+	}
+
 	// Handle multiple image URLs if provided
 	if len(req.ImageURLs) > 0 {
 		for i, url := range req.ImageURLs {
@@ -66,11 +91,29 @@ func (u *ProductUseCase) CreateProduct(ctx context.Context, req domain.CreatePro
 		return nil, err
 	}
 
+	changes := diffProducts(&domain.Product{}, product)
+	recordRevision(ctx, u.revisionRepo, u.logger, product.ID, domain.RevisionActionCreate, changes)
+
 	return product, nil
 }
 
 // CreateProductWithImages creates a new product with both uploaded images and image URLs
 func (u *ProductUseCase) CreateProductWithImages(ctx context.Context, req domain.CreateProductRequest, uploadedImages []domain.ProductImage) (*domain.Product, error) {
+	product, err := u.newProduct(ctx, req, uploadedImages)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := u.createProduct(ctx, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// newProduct builds a product from a create request with uploaded images
+// first and image URLs after them, without saving it
+func (u *ProductUseCase) newProduct(ctx context.Context, req domain.CreateProductRequest, uploadedImages []domain.ProductImage) (*domain.Product, error) {
 	product := &domain.Product{
 		Name:        req.Name,
 		Description: req.Description,
@@ -82,6 +125,16 @@ func (u *ProductUseCase) CreateProductWithImages(ctx context.Context, req domain
 		IsActive:    true,
 	}
 
+	if err := applyVariants(product, req.Variants); err != nil {
+		return nil, err
+	}
+	if err := u.applyCodes(ctx, product, req.SKU, req.Barcodes); err != nil {
+		return nil, err
+	}
+	if err := u.applySpecs(ctx, product, req.Specs); err != nil {
+		return nil, err
+	}
+
 	// Add uploaded images first
 	product.Images = append(product.Images, uploadedImages...)
 
@@ -125,12 +178,18 @@ func (u *ProductUseCase) CreateProductWithImages(ctx context.Context, req domain
 		}
 	}
 
-	err := u.productRepo.Create(ctx, product)
-	if err != nil {
-		return nil, err
+	return product, nil
+}
+
+// createProduct saves a new product and records its creation
+func (u *ProductUseCase) createProduct(ctx context.Context, product *domain.Product) error {
+	if err := u.productRepo.Create(ctx, product); err != nil {
+		return err
 	}
 
-	return product, nil
+	changes := diffProducts(&domain.Product{}, product)
+	recordRevision(ctx, u.revisionRepo, u.logger, product.ID, domain.RevisionActionCreate, changes)
+	return nil
 }
 
 // GetProductByID retrieves a product by ID
@@ -145,17 +204,41 @@ func (u *ProductUseCase) GetProductByID(ctx context.Context, id primitive.Object
 	return product, nil
 }
 
-// UpdateProduct updates a product
-func (u *ProductUseCase) UpdateProduct(ctx context.Context, id primitive.ObjectID, req domain.UpdateProductRequest) (*domain.Product, error) {
-	// Get existing product
-	product, err := u.productRepo.GetByID(ctx, id)
+// UpdateProduct updates a product at the version the client last read
+func (u *ProductUseCase) UpdateProduct(ctx context.Context, id primitive.ObjectID, version int64, req domain.UpdateProductRequest) (*domain.Product, error) {
+	product, err := u.getProductAtVersion(ctx, id, version)
 	if err != nil {
 		return nil, err
 	}
-	if product == nil {
-		return nil, errors.New("product not found")
+	before := cloneProduct(product)
+
+	if err := u.applyUpdate(ctx, product, req); err != nil {
+		return nil, err
+	}
+
+	if err := u.updateProduct(ctx, before, product); err != nil {
+		return nil, err
+	}
+
+	return product, nil
+}
+
+// getProductAtVersion retrieves a product to change and checks that it is
+// still at the version the client last read
+func (u *ProductUseCase) getProductAtVersion(ctx context.Context, id primitive.ObjectID, version int64) (*domain.Product, error) {
+	product, err := u.GetProductByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product.Version != version {
+		return nil, errors.New("version mismatch")
 	}
+	return product, nil
+}
 
+// applyUpdate applies an update request to a product without saving it.
+// Image URLs replace the URL-based images and keep uploaded ones.
+func (u *ProductUseCase) applyUpdate(ctx context.Context, product *domain.Product, req domain.UpdateProductRequest) error {
 	// Update fields
 	if req.Name != "" {
 		product.Name = req.Name
@@ -199,8 +282,8 @@ func (u *ProductUseCase) UpdateProduct(ctx context.Context, id primitive.ObjectI
 			}, product.Images...)
 		}
 	}
-	if req.Stock >= 0 {
-		product.Stock = req.Stock
+	if req.Stock != nil {
+		product.Stock = *req.Stock
 	}
 	if req.IsActive != nil {
 		product.IsActive = *req.IsActive
@@ -232,24 +315,38 @@ func (u *ProductUseCase) UpdateProduct(ctx context.Context, id primitive.ObjectI
 		product.Images = newImages
 	}
 
-	err = u.productRepo.Update(ctx, product)
-	if err != nil {
-		return nil, err
+	if err := applyVariants(product, req.Variants); err != nil {
+		return err
+	}
+	if err := u.applyCodes(ctx, product, req.SKU, req.Barcodes); err != nil {
+		return err
+	}
+	if err := u.applySpecs(ctx, product, req.Specs); err != nil {
+		return err
 	}
 
-	return product, nil
+	return nil
 }
 
-// UpdateProductWithImages updates a product with both uploaded images and image URLs
-func (u *ProductUseCase) UpdateProductWithImages(ctx context.Context, id primitive.ObjectID, req domain.UpdateProductRequest, uploadedImages []domain.ProductImage) (*domain.Product, error) {
-	// Get existing product
-	product, err := u.productRepo.GetByID(ctx, id)
+// updateProduct saves a changed product and records the changes
+func (u *ProductUseCase) updateProduct(ctx context.Context, before, product *domain.Product) error {
+	if err := u.productRepo.Update(ctx, product); err != nil {
+		return err
+	}
+
+	changes := diffProducts(before, product)
+	recordRevision(ctx, u.revisionRepo, u.logger, product.ID, domain.RevisionActionUpdate, changes)
+	return nil
+}
+
+// UpdateProductWithImages updates a product with both uploaded images and
+// image URLs at the version the client last read
+func (u *ProductUseCase) UpdateProductWithImages(ctx context.Context, id primitive.ObjectID, version int64, req domain.UpdateProductRequest, uploadedImages []domain.ProductImage) (*domain.Product, error) {
+	product, err := u.getProductAtVersion(ctx, id, version)
 	if err != nil {
 		return nil, err
 	}
-	if product == nil {
-		return nil, errors.New("product not found")
-	}
+	before := cloneProduct(product)
 
 	// Update basic fields
 	if req.Name != "" {
@@ -267,8 +364,8 @@ func (u *ProductUseCase) UpdateProductWithImages(ctx context.Context, id primiti
 	if req.Brand != "" {
 		product.Brand = req.Brand
 	}
-	if req.Stock >= 0 {
-		product.Stock = req.Stock
+	if req.Stock != nil {
+		product.Stock = *req.Stock
 	}
 	if req.IsActive != nil {
 		product.IsActive = *req.IsActive
@@ -324,16 +421,29 @@ func (u *ProductUseCase) UpdateProductWithImages(ctx context.Context, id primiti
 		}
 	}
 
+	if err := applyVariants(product, req.Variants); err != nil {
+		return nil, err
+	}
+	if err := u.applyCodes(ctx, product, req.SKU, req.Barcodes); err != nil {
+		return nil, err
+	}
+	if err := u.applySpecs(ctx, product, req.Specs); err != nil {
+		return nil, err
+	}
+
 	err = u.productRepo.Update(ctx, product)
 	if err != nil {
 		return nil, err
 	}
 
+	changes := diffProducts(before, product)
+	recordRevision(ctx, u.revisionRepo, u.logger, product.ID, domain.RevisionActionUpdate, changes)
+
 	return product, nil
 }
 
 // DeleteProduct deletes a product
-func (u *ProductUseCase) DeleteProduct(ctx context.Context, id primitive.ObjectID) error {
+func (u *ProductUseCase) DeleteProduct(ctx context.Context, id, deletedBy primitive.ObjectID) error {
 	// Check if product exists
 	product, err := u.productRepo.GetByID(ctx, id)
 	if err != nil {
@@ -343,17 +453,110 @@ func (u *ProductUseCase) DeleteProduct(ctx context.Context, id primitive.ObjectI
 		return errors.New("product not found")
 	}
 
-	return u.productRepo.Delete(ctx, id)
+	// Products are moved to the trash, sales keep referring to them
+	if err := u.productRepo.SoftDelete(ctx, id, deletedBy); err != nil {
+		return err
+	}
+
+	recordRevision(ctx, u.revisionRepo, u.logger, id, domain.RevisionActionDelete, nil)
+	return nil
+}
+
+// GetDeletedProducts retrieves the products in the trash, most recently
+// deleted first unless another sort is given
+func (u *ProductUseCase) GetDeletedProducts(ctx context.Context, filter domain.ProductFilter) ([]*domain.Product, int64, error) {
+	if err := validateProductListFilter(&filter); err != nil {
+		return nil, 0, err
+	}
+	if filter.Sort == "" {
+		filter.Sort = "deleted_at"
+	}
+	filter.Deleted = true
+
+	products, err := u.productRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := u.productRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return products, count, nil
+}
+
+// RestoreProduct moves a product out of the trash
+func (u *ProductUseCase) RestoreProduct(ctx context.Context, id primitive.ObjectID) (*domain.Product, error) {
+	product, err := u.productRepo.GetDeletedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, errors.New("product not found in trash")
+	}
+
+	if err := u.productRepo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	recordRevision(ctx, u.revisionRepo, u.logger, id, domain.RevisionActionRestore, nil)
+
+	return u.productRepo.GetByID(ctx, id)
+}
+
+// PurgeDeletedProducts permanently deletes the products moved to the trash
+// before a time, together with their uploaded images and part compatibility.
+// A product whose images cannot be deleted stays in the trash for the next purge.
+func (u *ProductUseCase) PurgeDeletedProducts(ctx context.Context, before time.Time) (int, error) {
+	products, err := u.productRepo.ListDeletedBefore(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	var errs []error
+	for _, product := range products {
+		if err := u.deleteImageFiles(product.Images); err != nil {
+			errs = append(errs, fmt.Errorf("product %s: %w", product.ID.Hex(), err))
+			continue
+		}
+		if err := u.productRepo.Delete(ctx, product.ID); err != nil {
+			errs = append(errs, fmt.Errorf("product %s: %w", product.ID.Hex(), err))
+			continue
+		}
+		if err := u.compatibilityRepo.DeleteByProduct(ctx, product.ID); err != nil {
+			errs = append(errs, fmt.Errorf("product %s: %w", product.ID.Hex(), err))
+		}
+		purged++
+	}
+
+	return purged, errors.Join(errs...)
+}
+
+// deleteImageFiles deletes the uploaded files of product images. Files that
+// are already gone are skipped.
+func (u *ProductUseCase) deleteImageFiles(images []domain.ProductImage) error {
+	for _, image := range images {
+		if image.IsURL || image.FilePath == "" {
+			continue
+		}
+		if err := u.uploadConfig.DeleteFile(image.FilePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetProducts retrieves products with filtering and pagination
 func (u *ProductUseCase) GetProducts(ctx context.Context, filter domain.ProductFilter) ([]*domain.Product, int64, error) {
-	// Set default pagination values
-	if filter.Page <= 0 {
-		filter.Page = 1
+	if err := validateProductListFilter(&filter); err != nil {
+		return nil, 0, err
 	}
-	if filter.Limit <= 0 {
-		filter.Limit = 10
+
+	// Get total count
+	filter, count, err := u.resolveSearch(ctx, filter)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	// Get products
@@ -362,11 +565,247 @@ func (u *ProductUseCase) GetProducts(ctx context.Context, filter domain.ProductF
 		return nil, 0, err
 	}
 
-	// Get total count
+	if terms := utils.SearchTerms(filter.Search); len(terms) > 0 {
+		pattern := searchPattern(terms, filter.PartialSearch)
+		for _, product := range products {
+			product.Highlights = highlightProduct(product, pattern)
+		}
+	}
+
+	return products, count, nil
+}
+
+// applyVariants replaces the variants of a product with the requested ones
+// and updates the product price and stock from them. A nil list keeps the
+// current variants. Requested variants with an ID update that variant.
+func applyVariants(product *domain.Product, reqs []domain.ProductVariantRequest) error {
+	if reqs != nil {
+		existing := make(map[string]bool, len(product.Variants))
+		for _, variant := range product.Variants {
+			existing[variant.ID] = true
+		}
+
+		seenIDs := make(map[string]bool, len(reqs))
+		seenSKUs := make(map[string]bool, len(reqs))
+		variants := make([]domain.ProductVariant, 0, len(reqs))
+		for _, req := range reqs {
+			sku := strings.TrimSpace(req.SKU)
+			if sku == "" {
+				return errors.New("variant SKU is required")
+			}
+			if req.Price <= 0 {
+				return errors.New("variant price must be greater than 0")
+			}
+			if req.Stock < 0 {
+				return errors.New("variant stock cannot be negative")
+			}
+			if seenSKUs[strings.ToUpper(sku)] {
+				return errors.New("duplicate variant SKU")
+			}
+			seenSKUs[strings.ToUpper(sku)] = true
+
+			id := req.ID
+			if id == "" {
+				id = uuid.New().String()
+			} else if !existing[id] || seenIDs[id] {
+				return errors.New("variant not found")
+			}
+			seenIDs[id] = true
+
+			isActive := true
+			if req.IsActive != nil {
+				isActive = *req.IsActive
+			}
+
+			barcodes, err := normalizeBarcodes(req.Barcodes)
+			if err != nil {
+				return err
+			}
+
+			variants = append(variants, domain.ProductVariant{
+				ID:         id,
+				SKU:        sku,
+				Barcodes:   barcodes,
+				Name:       req.Name,
+				Attributes: req.Attributes,
+				Price:      req.Price,
+				Stock:      req.Stock,
+				IsActive:   isActive,
+			})
+		}
+		product.Variants = variants
+	}
+
+	if len(product.Variants) == 0 {
+		return nil
+	}
+
+	// The product shows the lowest active variant price and the total stock
+	var price float64
+	stock := 0
+	for _, variant := range product.Variants {
+		stock += variant.Stock
+		if variant.IsActive && (price == 0 || variant.Price < price) {
+			price = variant.Price
+		}
+	}
+	if price > 0 {
+		product.Price = price
+	}
+	product.Stock = stock
+
+	return nil
+}
+
+// resolveVariant returns the variant a stock change applies to. Products with
+// variants need a variant ID, for other products it must be empty.
+func resolveVariant(product *domain.Product, variantID string) (*domain.ProductVariant, error) {
+	if variantID == "" {
+		if len(product.Variants) > 0 {
+			return nil, errors.New("variant is required for products with variants")
+		}
+		return nil, nil
+	}
+
+	for i := range product.Variants {
+		if product.Variants[i].ID == variantID {
+			return &product.Variants[i], nil
+		}
+	}
+	return nil, errors.New("variant not found")
+}
+
+// resolveSaleVariant returns the variant a sale is for. Inactive variants
+// keep their stock but are not sold.
+func resolveSaleVariant(product *domain.Product, variantID string) (*domain.ProductVariant, error) {
+	variant, err := resolveVariant(product, variantID)
+	if err != nil {
+		return nil, err
+	}
+	if variant != nil && !variant.IsActive {
+		return nil, errors.New("variant is not available for sale")
+	}
+	return variant, nil
+}
+
+// GetProductsByCursor retrieves a page of products using keyset pagination
+// and returns the cursor of the next page, which is empty on the last page
+func (u *ProductUseCase) GetProductsByCursor(ctx context.Context, filter domain.ProductFilter) ([]*domain.Product, string, error) {
+	if filter.Cursor == nil {
+		empty := ""
+		filter.Cursor = &empty
+	}
+	if err := validateProductListFilter(&filter); err != nil {
+		return nil, "", err
+	}
+
+	products, nextCursor, err := u.productRepo.ListWithCursor(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Only pages with a next page get a cursor, so an empty page means the
+	// full-text search matched nothing, on the first page or on the pages of
+	// a search that already fell back to matching parts of words
+	if len(products) == 0 && filter.Search != "" && !filter.PartialSearch {
+		filter.PartialSearch = true
+		products, nextCursor, err = u.productRepo.ListWithCursor(ctx, filter)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if terms := utils.SearchTerms(filter.Search); len(terms) > 0 {
+		pattern := searchPattern(terms, filter.PartialSearch)
+		for _, product := range products {
+			product.Highlights = highlightProduct(product, pattern)
+		}
+	}
+
+	return products, nextCursor, nil
+}
+
+// BackfillSoldCounts sets the sold count, used to sort by popularity, of
+// products created before it was tracked
+func (u *ProductUseCase) BackfillSoldCounts(ctx context.Context) (int64, error) {
+	return u.productRepo.BackfillSoldCounts(ctx)
+}
+
+// GetProductFacets counts the products matching the filter per category,
+// brand, price range and availability
+func (u *ProductUseCase) GetProductFacets(ctx context.Context, filter domain.ProductFilter) (*domain.ProductFacets, error) {
+	filter, _, err := u.resolveSearch(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return u.productRepo.GetFacets(ctx, filter)
+}
+
+// resolveSearch counts the products matching the filter. Full-text search
+// only matches whole words, so "trac" finds nothing; in that case the filter
+// falls back to matching parts of words.
+func (u *ProductUseCase) resolveSearch(ctx context.Context, filter domain.ProductFilter) (domain.ProductFilter, int64, error) {
 	count, err := u.productRepo.Count(ctx, filter)
 	if err != nil {
-		return nil, 0, err
+		return filter, 0, err
 	}
 
-	return products, count, nil
+	if count == 0 && filter.Search != "" && !filter.PartialSearch {
+		filter.PartialSearch = true
+		count, err = u.productRepo.Count(ctx, filter)
+		if err != nil {
+			return filter, 0, err
+		}
+	}
+
+	return filter, count, nil
+}
+
+// productSortOptions are the sort options of product lists
+var productSortOptions = map[string]bool{
+	"price":      true,
+	"name":       true,
+	"stock":      true,
+	"created_at": true,
+	"popularity": true,
+	"relevance":  true,
+}
+
+// validateProductListFilter checks the sort of a product filter and applies
+// the default and maximum page size
+func validateProductListFilter(filter *domain.ProductFilter) error {
+	if filter.Sort != "" && !productSortOptions[filter.Sort] {
+		return errors.New("invalid sort field")
+	}
+	if filter.Sort == "relevance" {
+		if filter.Search == "" {
+			return errors.New("relevance sorting requires a search")
+		}
+		if filter.Cursor != nil {
+			return errors.New("cursor pagination does not support relevance sorting")
+		}
+	}
+	if err := validateSortOrder(filter.Order); err != nil {
+		return err
+	}
+
+	// Set default pagination values
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+	if filter.Limit > domain.MaxPageLimit {
+		filter.Limit = domain.MaxPageLimit
+	}
+	return nil
+}
+
+// validateSortOrder checks that a sort order is asc, desc or empty
+func validateSortOrder(order string) error {
+	if order != "" && order != domain.SortAscending && order != domain.SortDescending {
+		return errors.New("invalid sort order")
+	}
+	return nil
 }
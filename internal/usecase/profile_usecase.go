@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxProfileSessions is the number of sessions shown on the profile
+const maxProfileSessions = 10
+
+// GetProfile returns the account settings of a user: the user itself, the
+// permissions of their role and their active sessions
+func (u *AuthUseCase) GetProfile(ctx context.Context, userID primitive.ObjectID) (*domain.ProfileResponse, error) {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := u.GetPermissions(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := u.tokenRepo.ListActiveRefreshTokens(ctx, user.ID, maxProfileSessions)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*domain.Session, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, &domain.Session{
+			ID:         token.FamilyID,
+			IPAddress:  token.IPAddress,
+			UserAgent:  token.UserAgent,
+			StartedAt:  token.SessionStartedAt,
+			LastUsedAt: token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+		})
+	}
+
+	return &domain.ProfileResponse{
+		User:        *user,
+		Permissions: permissions,
+		Sessions:    sessions,
+	}, nil
+}
+
+// UpdateProfile updates the name and email of a user. A new email is not
+// applied right away: a confirmation link is sent to it and the current
+// email stays in use until the link is followed.
+func (u *AuthUseCase) UpdateProfile(ctx context.Context, userID primitive.ObjectID, req domain.UpdateProfileRequest) (*domain.User, error) {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		user.Name = name
+	}
+
+	email := strings.TrimSpace(req.Email)
+	changeEmail := email != "" && email != user.Email
+	if email == user.Email {
+		// Changing back to the current email cancels a pending change
+		user.PendingEmail = ""
+	}
+
+	if changeEmail {
+		if req.CurrentPassword == "" {
+			return nil, errors.New("current password is required to change the email")
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+			return nil, errors.New("current password is incorrect")
+		}
+
+		existingUser, err := u.userRepo.GetByEmail(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		if existingUser != nil {
+			return nil, errors.New("email is already in use")
+		}
+
+		user.PendingEmail = email
+	}
+
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if changeEmail {
+		if err := u.sendEmailChangeConfirmation(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// ConfirmEmailChange applies a pending email change using the token sent to
+// the new address. The previous address is notified of the change.
+func (u *AuthUseCase) ConfirmEmailChange(ctx context.Context, req domain.ConfirmEmailChangeRequest) (*domain.User, error) {
+	stored, err := u.tokenRepo.GetUserTokenByHash(ctx, domain.TokenPurposeEmailChange, hashToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil || stored.UsedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, errors.New("invalid or expired email confirmation token")
+	}
+
+	user, err := u.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive || user.PendingEmail == "" {
+		return nil, errors.New("invalid or expired email confirmation token")
+	}
+
+	// Redeem the token first so it cannot be used twice concurrently
+	used, err := u.tokenRepo.MarkUserTokenUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, errors.New("invalid or expired email confirmation token")
+	}
+
+	// The address may have been registered since the change was requested
+	existingUser, err := u.userRepo.GetByEmail(ctx, user.PendingEmail)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser != nil {
+		return nil, errors.New("email is already in use")
+	}
+
+	// Following the link verified the new address
+	now := time.Now()
+	previousEmail := user.Email
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	user.EmailVerifiedAt = &now
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := u.tokenRepo.DeleteUserTokens(ctx, user.ID, domain.TokenPurposeEmailChange); err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf(
+		"Hello %s,\n\nThe email address of your account was changed to %s. If you did not make this change, contact support immediately.\n",
+		user.Name, user.Email,
+	)
+	if err := u.mailer.Send(ctx, domain.EmailMessage{
+		To:      previousEmail,
+		Subject: "Your email address was changed",
+		Body:    body,
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// DeleteAccount closes the account of a user after checking their password
+// and, when MFA is enabled, a code. The access token used for the request,
+// every session of the user and the API keys acting as the user are revoked.
+func (u *AuthUseCase) DeleteAccount(ctx context.Context, userID primitive.ObjectID, tokenID string, tokenExpiresAt time.Time, req domain.DeleteAccountRequest) error {
+	user, err := u.getUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		return errors.New("password is incorrect")
+	}
+
+	if user.MFAEnabled {
+		if err := u.verifyMFACode(ctx, user, req.Code, "", req.IPAddress, req.UserAgent); err != nil {
+			return err
+		}
+	}
+
+	// The store must keep at least one administrator
+	if user.Role == domain.RoleAdmin {
+		isActive := true
+		admins, err := u.userRepo.Count(ctx, domain.UserFilter{Role: domain.RoleAdmin, IsActive: &isActive})
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return errors.New("cannot delete the last administrator account")
+		}
+	}
+
+	if err := u.Logout(ctx, user.ID, tokenID, tokenExpiresAt, domain.LogoutRequest{AllSessions: true}); err != nil {
+		return err
+	}
+
+	for _, purpose := range []string{domain.TokenPurposePasswordReset, domain.TokenPurposeEmailChange, domain.TokenPurposeEmailVerification} {
+		if err := u.tokenRepo.DeleteUserTokens(ctx, user.ID, purpose); err != nil {
+			return err
+		}
+	}
+
+	if err := u.apiKeyRepo.RevokeByUser(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return u.userRepo.Delete(ctx, user.ID)
+}
+
+// sendEmailChangeConfirmation emails a one-time confirmation link to the pending email of a user
+func (u *AuthUseCase) sendEmailChangeConfirmation(ctx context.Context, user *domain.User) error {
+	// Only the link for the most recently requested address is valid
+	if err := u.tokenRepo.DeleteUserTokens(ctx, user.ID, domain.TokenPurposeEmailChange); err != nil {
+		return err
+	}
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(u.config.EmailVerificationTTL)
+	err = u.tokenRepo.CreateUserToken(ctx, &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   domain.TokenPurposeEmailChange,
+		TokenHash: hashToken(token),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		"Hello %s,\n\nPlease confirm %s as the new email address of your account:\n\n%s\n\nThe link expires at %s. Until then your current address stays in use. If you did not request this change you can ignore this email.\n",
+		user.Name, user.PendingEmail, tokenLink(u.config.EmailChangeURL, token), expiresAt.Format(time.RFC1123),
+	)
+
+	return u.mailer.Send(ctx, domain.EmailMessage{
+		To:      user.PendingEmail,
+		Subject: "Confirm your new email address",
+		Body:    body,
+	})
+}
@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// roleNamePattern restricts role names to lowercase identifiers
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// RoleUseCase handles role and permission business logic
+type RoleUseCase struct {
+	roleRepo domain.RoleRepository
+	userRepo domain.UserRepository
+}
+
+// NewRoleUseCase creates a new role use case
+func NewRoleUseCase(roleRepo domain.RoleRepository, userRepo domain.UserRepository) *RoleUseCase {
+	return &RoleUseCase{
+		roleRepo: roleRepo,
+		userRepo: userRepo,
+	}
+}
+
+// EnsureDefaultRoles creates the built-in roles that do not exist yet.
+// Existing roles are left untouched so admins can customize them.
+func (u *RoleUseCase) EnsureDefaultRoles(ctx context.Context) error {
+	for _, role := range domain.DefaultRoles() {
+		existing, err := u.roleRepo.GetByName(ctx, role.Name)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			continue
+		}
+
+		role := role
+		role.IsSystem = true
+		if err := u.roleRepo.Create(ctx, &role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListRoles retrieves all roles
+func (u *RoleUseCase) ListRoles(ctx context.Context) ([]*domain.Role, error) {
+	return u.roleRepo.List(ctx)
+}
+
+// GetRole retrieves a role by name
+func (u *RoleUseCase) GetRole(ctx context.Context, name string) (*domain.Role, error) {
+	role, err := u.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errors.New("role not found")
+	}
+	return role, nil
+}
+
+// CreateRole creates a custom role
+func (u *RoleUseCase) CreateRole(ctx context.Context, req domain.CreateRoleRequest) (*domain.Role, error) {
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, errors.New("invalid role name")
+	}
+	if err := validatePermissions(req.Permissions); err != nil {
+		return nil, err
+	}
+
+	existing, err := u.roleRepo.GetByName(ctx, req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("role already exists")
+	}
+
+	role := &domain.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}
+
+	if err := u.roleRepo.Create(ctx, role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// UpdateRole updates the description and permissions of a role
+func (u *RoleUseCase) UpdateRole(ctx context.Context, name string, req domain.UpdateRoleRequest) (*domain.Role, error) {
+	role, err := u.GetRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Changing the admin role could lock everyone out
+	if role.Name == domain.RoleAdmin && req.Permissions != nil {
+		return nil, errors.New("admin role permissions cannot be modified")
+	}
+
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+	if req.Permissions != nil {
+		if err := validatePermissions(req.Permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = req.Permissions
+	}
+
+	if err := u.roleRepo.Update(ctx, role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// DeleteRole deletes a custom role that is not assigned to any user
+func (u *RoleUseCase) DeleteRole(ctx context.Context, name string) error {
+	role, err := u.GetRole(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role.IsSystem {
+		return errors.New("system roles cannot be deleted")
+	}
+
+	count, err := u.userRepo.Count(ctx, domain.UserFilter{Role: role.Name})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("role is assigned to users")
+	}
+
+	return u.roleRepo.Delete(ctx, role.ID)
+}
+
+// validatePermissions checks that every permission is known to the system
+func validatePermissions(permissions []string) error {
+	for _, p := range permissions {
+		if !domain.IsValidPermission(p) {
+			return fmt.Errorf("unknown permission %q", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,364 @@
+package usecase
+
+import (
+	"agricultural-equipment-store/internal/domain"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserUseCase handles user management business logic
+type UserUseCase struct {
+	userRepo  domain.UserRepository
+	roleRepo  domain.RoleRepository
+	tokenRepo domain.TokenRepository
+
+	failedLoginRepo domain.FailedLoginRepository
+	apiKeyRepo      domain.APIKeyRepository
+}
+
+// NewUserUseCase creates a new user use case
+func NewUserUseCase(userRepo domain.UserRepository, roleRepo domain.RoleRepository, tokenRepo domain.TokenRepository, failedLoginRepo domain.FailedLoginRepository, apiKeyRepo domain.APIKeyRepository) *UserUseCase {
+	return &UserUseCase{
+		userRepo:  userRepo,
+		roleRepo:  roleRepo,
+		tokenRepo: tokenRepo,
+
+		failedLoginRepo: failedLoginRepo,
+		apiKeyRepo:      apiKeyRepo,
+	}
+}
+
+// CreateUser creates a staff account with the given role. actorPermissions
+// are the permissions of the user creating the account, which must include
+// every permission of the role.
+func (u *UserUseCase) CreateUser(ctx context.Context, actorPermissions []string, req domain.AdminCreateUserRequest) (*domain.User, error) {
+	role, err := u.validateRole(ctx, req.Role)
+	if err != nil {
+		return nil, err
+	}
+	if !canGrantRole(actorPermissions, role) {
+		return nil, errors.New("cannot grant a role with permissions you do not have")
+	}
+
+	existingUser, err := u.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser != nil {
+		return nil, errors.New("user already exists")
+	}
+
+	hashedPassword, err := hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Staff accounts are set up by an admin who vouches for the address
+	now := time.Now()
+	user := &domain.User{
+		Email:           req.Email,
+		Password:        hashedPassword,
+		Name:            req.Name,
+		Role:            req.Role,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+	}
+
+	if err := u.userRepo.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// MarkLegacyEmailsVerified treats the emails of users created before email
+// verification was introduced as verified, so they are not locked out
+func (u *UserUseCase) MarkLegacyEmailsVerified(ctx context.Context) (int64, error) {
+	return u.userRepo.MarkLegacyEmailsVerified(ctx)
+}
+
+// ListUsers retrieves users with filtering and pagination
+func (u *UserUseCase) ListUsers(ctx context.Context, filter domain.UserFilter) ([]*domain.User, int64, error) {
+	// Set default pagination values
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+
+	users, err := u.userRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := u.userRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, count, nil
+}
+
+// GetUser retrieves a user by ID
+func (u *UserUseCase) GetUser(ctx context.Context, id primitive.ObjectID) (*domain.User, error) {
+	user, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// SetUserActive activates or deactivates a user. Deactivated users are logged
+// out of every session. The last active administrator cannot be deactivated.
+func (u *UserUseCase) SetUserActive(ctx context.Context, actorID primitive.ObjectID, actorPermissions []string, id primitive.ObjectID, isActive bool) (*domain.User, error) {
+	if actorID == id && !isActive {
+		return nil, errors.New("cannot deactivate your own account")
+	}
+
+	user, err := u.getManagedUser(ctx, actorPermissions, id)
+	if err != nil {
+		return nil, err
+	}
+	if !isActive {
+		if err := u.checkOtherAdmins(ctx, user, "cannot deactivate the last administrator account"); err != nil {
+			return nil, err
+		}
+	}
+
+	user.IsActive = isActive
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if !isActive {
+		if err := u.tokenRepo.RevokeUserRefreshTokens(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// ChangeUserRole changes the role of a user. The actor must hold every
+// permission of both the new role and the user's current role, so users
+// cannot grant or take away more than they have themselves.
+func (u *UserUseCase) ChangeUserRole(ctx context.Context, actorID primitive.ObjectID, actorPermissions []string, id primitive.ObjectID, role string) (*domain.User, error) {
+	newRole, err := u.validateRole(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	if !canGrantRole(actorPermissions, newRole) {
+		return nil, errors.New("cannot grant a role with permissions you do not have")
+	}
+	if actorID == id {
+		return nil, errors.New("cannot change your own role")
+	}
+
+	user, err := u.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// A role that no longer exists grants nothing
+	currentRole, err := u.roleRepo.GetByName(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	if currentRole != nil && !canGrantRole(actorPermissions, currentRole) {
+		return nil, errors.New("cannot change the role of a user with permissions you do not have")
+	}
+
+	user.Role = role
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// Force a new login so the role change takes effect
+	if err := u.tokenRepo.RevokeUserRefreshTokens(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// SetPassword sets a new password for a user and ends all of their sessions
+func (u *UserUseCase) SetPassword(ctx context.Context, id primitive.ObjectID, password string) error {
+	user, err := u.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.Password = hashedPassword
+	user.PasswordChangedAt = &now
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if err := u.userRepo.ResetFailedLogins(ctx, id); err != nil {
+		return err
+	}
+
+	return u.tokenRepo.RevokeUserRefreshTokens(ctx, id)
+}
+
+// UnlockUser clears the failed login counter of a user and lifts any lockout
+func (u *UserUseCase) UnlockUser(ctx context.Context, actorPermissions []string, id primitive.ObjectID) (*domain.User, error) {
+	if _, err := u.getManagedUser(ctx, actorPermissions, id); err != nil {
+		return nil, err
+	}
+
+	if err := u.userRepo.ResetFailedLogins(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return u.GetUser(ctx, id)
+}
+
+// ResetMFA turns off two-factor authentication for a user who lost their
+// authenticator and recovery codes. They can enroll again after logging in.
+func (u *UserUseCase) ResetMFA(ctx context.Context, actorPermissions []string, id primitive.ObjectID) (*domain.User, error) {
+	user, err := u.getManagedUser(ctx, actorPermissions, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.MFAEnabled = false
+	user.MFAEnabledAt = nil
+	user.MFASecret = ""
+	user.MFAPendingSecret = ""
+	user.MFARecoveryCodes = nil
+	if err := u.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	// End existing sessions, they were established with the old second factor
+	if err := u.tokenRepo.RevokeUserRefreshTokens(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ListFailedLogins retrieves the failed login audit trail with filtering and pagination
+func (u *UserUseCase) ListFailedLogins(ctx context.Context, filter domain.FailedLoginFilter) ([]*domain.FailedLogin, int64, error) {
+	// Set default pagination values
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 10
+	}
+
+	attempts, err := u.failedLoginRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := u.failedLoginRepo.Count(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return attempts, count, nil
+}
+
+// DeleteUser deletes a user, ends all of their sessions and revokes the API
+// keys acting as them. The last active administrator cannot be deleted.
+func (u *UserUseCase) DeleteUser(ctx context.Context, actorID primitive.ObjectID, actorPermissions []string, id primitive.ObjectID) error {
+	if actorID == id {
+		return errors.New("cannot delete your own account")
+	}
+
+	user, err := u.getManagedUser(ctx, actorPermissions, id)
+	if err != nil {
+		return err
+	}
+	if err := u.checkOtherAdmins(ctx, user, "cannot delete the last administrator account"); err != nil {
+		return err
+	}
+
+	if err := u.tokenRepo.RevokeUserRefreshTokens(ctx, id); err != nil {
+		return err
+	}
+	if err := u.apiKeyRepo.RevokeByUser(ctx, id); err != nil {
+		return err
+	}
+
+	return u.userRepo.Delete(ctx, id)
+}
+
+// getManagedUser retrieves a user the actor may manage. Like granting a role,
+// managing a user requires every permission of the user's role, so users
+// cannot lock out, delete or take over accounts with more permissions.
+func (u *UserUseCase) getManagedUser(ctx context.Context, actorPermissions []string, id primitive.ObjectID) (*domain.User, error) {
+	user, err := u.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// A role that no longer exists grants nothing
+	role, err := u.roleRepo.GetByName(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	if role != nil && !canGrantRole(actorPermissions, role) {
+		return nil, errors.New("cannot manage a user with permissions you do not have")
+	}
+	return user, nil
+}
+
+// checkOtherAdmins returns an error with the message when the user is the
+// last active administrator, as the store must keep at least one
+func (u *UserUseCase) checkOtherAdmins(ctx context.Context, user *domain.User, message string) error {
+	if user.Role != domain.RoleAdmin || !user.IsActive {
+		return nil
+	}
+
+	isActive := true
+	admins, err := u.userRepo.Count(ctx, domain.UserFilter{Role: domain.RoleAdmin, IsActive: &isActive})
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return errors.New(message)
+	}
+	return nil
+}
+
+// validateRole retrieves a role and checks that it exists
+func (u *UserUseCase) validateRole(ctx context.Context, name string) (*domain.Role, error) {
+	role, err := u.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if role == nil {
+		return nil, errors.New("invalid role")
+	}
+	return role, nil
+}
+
+// canGrantRole reports whether a user with the granted permissions may give
+// a role to others: they must hold every permission of the role, or be able
+// to manage roles and so define any role anyway
+func canGrantRole(granted []string, role *domain.Role) bool {
+	if domain.HasPermission(granted, domain.PermissionRolesManage) {
+		return true
+	}
+	for _, permission := range role.Permissions {
+		if !domain.HasPermission(granted, permission) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package utils
+
+// IsDigits reports whether s is non-empty and consists of ASCII digits only
+func IsDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidGTINCheckDigit reports whether the last digit of a GTIN (EAN-8, UPC-A,
+// EAN-13 or GTIN-14) is the check digit of the digits before it
+func ValidGTINCheckDigit(code string) bool {
+	if len(code) < 2 || !IsDigits(code) {
+		return false
+	}
+
+	// Weights alternate 3 and 1, starting with 3 next to the check digit
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		digit := int(code[i] - '0')
+		if (len(code)-2-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+
+	return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
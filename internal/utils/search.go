@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxSearchTerms is the number of search terms that are used, the rest are ignored
+const MaxSearchTerms = 10
+
+// SearchTerms splits user input into plain search terms. Quotes and leading
+// or trailing punctuation are removed so the terms cannot be read as phrases
+// or negations by MongoDB text search.
+func SearchTerms(search string) []string {
+	var terms []string
+	for _, field := range strings.Fields(search) {
+		term := strings.TrimFunc(strings.ReplaceAll(field, `"`, ""), unicode.IsPunct)
+		if term == "" {
+			continue
+		}
+		terms = append(terms, term)
+		if len(terms) == MaxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
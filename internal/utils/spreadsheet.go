@@ -0,0 +1,427 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// csvFormulaPrefixes start cell values that spreadsheets evaluate as formulas
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// EscapeCSVFormula prefixes a value that a spreadsheet would evaluate as a
+// formula with an apostrophe, so opening a CSV file cannot run its cells.
+// Values that already look escaped get another apostrophe, so they are read
+// back unchanged.
+func EscapeCSVFormula(value string) string {
+	if value != "" && (strings.IndexByte(csvFormulaPrefixes, value[0]) >= 0 || unescapeCSVFormula(value) != value) {
+		return "'" + value
+	}
+	return value
+}
+
+// unescapeCSVFormula removes the apostrophe added by EscapeCSVFormula
+func unescapeCSVFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.IndexByte(csvFormulaPrefixes+"'", value[1]) >= 0 {
+		return value[1:]
+	}
+	return value
+}
+
+// ReadCSV reads all records of a CSV file. A UTF-8 byte order mark is
+// skipped and the delimiter is a semicolon when the header line has more
+// semicolons than commas, as spreadsheets in many locales export them.
+// Values escaped with EscapeCSVFormula are read as they were before.
+func ReadCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		header = data[:i]
+	}
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.New("invalid CSV file: " + err.Error())
+	}
+	for _, record := range records {
+		for i, value := range record {
+			record[i] = unescapeCSVFormula(value)
+		}
+	}
+	return records, nil
+}
+
+// ReadXLSX reads the cell values of the first worksheet of an XLSX workbook,
+// one slice per row. Missing rows and cells are returned as empty values.
+// The first row is a header: cells right of its last column are dropped, and
+// a sheet with values below maxRows rows after it is rejected.
+func ReadXLSX(r io.ReaderAt, size int64, maxRows int) ([][]string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.New("not an XLSX file")
+	}
+
+	files := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings []string
+	if file, ok := files["xl/sharedStrings.xml"]; ok {
+		if sharedStrings, err = readSharedStrings(file); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, errors.New("XLSX worksheet not found")
+	}
+	return readSheet(sheet, sharedStrings, maxRows)
+}
+
+// Worksheet size limits of the XLSX format
+const (
+	xlsxMaxRows    = 1 << 20
+	xlsxMaxColumns = 1 << 14
+)
+
+// xlsxMaxPartSize is the largest uncompressed size of a workbook part that is
+// read, so a small archive cannot expand into gigabytes of XML
+const xlsxMaxPartSize = 64 << 20
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is rich or plain text of a shared string or inline string cell
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var sb strings.Builder
+	for _, run := range t.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// firstSheetPath finds the part name of the first worksheet in the workbook
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook xlsxWorkbook
+	if err := decodeZipXML(files["xl/workbook.xml"], &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", errors.New("XLSX workbook has no worksheets")
+	}
+
+	var rels xlsxRelationships
+	if err := decodeZipXML(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].RelID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", errors.New("XLSX worksheet not found")
+}
+
+func readSharedStrings(file *zip.File) ([]string, error) {
+	var sst xlsxSharedStrings
+	if err := decodeZipXML(file, &sst); err != nil {
+		return nil, err
+	}
+	values := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		values[i] = item.String()
+	}
+	return values, nil
+}
+
+func readSheet(file *zip.File, sharedStrings []string, maxRows int) ([][]string, error) {
+	var sheet xlsxSheet
+	if err := decodeZipXML(file, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		// Rows without a reference follow the previous row
+		index := len(rows)
+		if row.Index > 0 {
+			index = row.Index - 1
+		}
+		if index < len(rows) || index >= xlsxMaxRows {
+			return nil, errors.New("invalid XLSX row order")
+		}
+
+		var values []string
+		for _, cell := range row.Cells {
+			column := len(values)
+			if cell.Ref != "" {
+				var ok bool
+				if column, ok = cellColumn(cell.Ref); !ok {
+					return nil, errors.New("invalid XLSX cell reference " + cell.Ref)
+				}
+			}
+			// Cells without a column in the header are never read
+			if len(rows) > 0 && column >= len(rows[0]) {
+				continue
+			}
+			for len(values) <= column {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				i, err := strconv.Atoi(cell.Value)
+				if err != nil || i < 0 || i >= len(sharedStrings) {
+					return nil, errors.New("invalid XLSX shared string in cell " + cell.Ref)
+				}
+				values[column] = sharedStrings[i]
+			case "inlineStr":
+				values[column] = cell.Inline.String()
+			case "b":
+				values[column] = strconv.FormatBool(cell.Value == "1")
+			default:
+				values[column] = cell.Value
+			}
+		}
+
+		// Rows without values, often only formatted, do not need to be read
+		if isBlankRow(values) {
+			continue
+		}
+		if index > maxRows {
+			return nil, fmt.Errorf("more than %d rows", maxRows)
+		}
+		for len(rows) < index {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, values)
+	}
+
+	return rows, nil
+}
+
+func isBlankRow(values []string) bool {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// cellColumn returns the zero-based column of a cell reference such as "AB12"
+func cellColumn(ref string) (int, bool) {
+	column := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		column = column*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || column > xlsxMaxColumns || !IsDigits(ref[i:]) {
+		return 0, false
+	}
+	return column - 1, true
+}
+
+func decodeZipXML(file *zip.File, v interface{}) error {
+	if file == nil {
+		return errors.New("not an XLSX file")
+	}
+	if file.UncompressedSize64 > xlsxMaxPartSize {
+		return errors.New("XLSX file is too large")
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	limited := io.LimitReader(rc, int64(file.UncompressedSize64))
+	if err := xml.NewDecoder(limited).Decode(v); err != nil {
+		return errors.New("invalid XLSX file")
+	}
+	return nil
+}
+
+// XLSXWriter writes an XLSX workbook with a single worksheet row by row, so
+// large sheets are streamed instead of built in memory
+type XLSXWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	rows    int
+}
+
+// NewXLSXWriter starts a workbook whose worksheet has the given name
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	archive := zip.NewWriter(w)
+
+	var name bytes.Buffer
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxPackageRels},
+		{"xl/workbook.xml", strings.Replace(xlsxWorkbookXML, "{name}", name.String(), 1)},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+
+	return &XLSXWriter{archive: archive, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Strings, numbers and booleans are written as cells
+// of that type and nil values as empty cells.
+func (x *XLSXWriter) WriteRow(values []interface{}) error {
+	x.rows++
+	var row bytes.Buffer
+	row.WriteString(`<row r="` + strconv.Itoa(x.rows) + `">`)
+	for i, value := range values {
+		ref := cellName(i) + strconv.Itoa(x.rows)
+		switch v := value.(type) {
+		case nil:
+			continue
+		case bool:
+			cell := "0"
+			if v {
+				cell = "1"
+			}
+			row.WriteString(`<c r="` + ref + `" t="b"><v>` + cell + `</v></c>`)
+		case int:
+			row.WriteString(`<c r="` + ref + `"><v>` + strconv.Itoa(v) + `</v></c>`)
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				continue
+			}
+			row.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatFloat(v, 'f', -1, 64) + `</v></c>`)
+		default:
+			row.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+			if err := xml.EscapeText(&row, []byte(fmt.Sprint(v))); err != nil {
+				return err
+			}
+			row.WriteString(`</t></is></c>`)
+		}
+	}
+	row.WriteString(`</row>`)
+
+	_, err := x.sheet.Write(row.Bytes())
+	return err
+}
+
+// Close finishes the worksheet and the workbook. It does not close the
+// underlying writer.
+func (x *XLSXWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return x.archive.Close()
+}
+
+// cellName returns the column letters of a zero-based column, such as "AB"
+func cellName(column int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxPackageRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbookXML = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="{name}" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`